@@ -21,6 +21,7 @@ Example:
             AccessToken:     "YOUR_ACCESS_TOKEN_THAT_YOU_WILL_GENERATE_FOR_YOUR_PAGE_ON_FACEBOOK",
             VerifyToken:     "YOUR_SECRET_TOKEN_FOR_VERIFYING_WEBHOOK_PUT_THE_SAME_VALUE_HERE_AND_ON_FB",
             PageID:          "YOUR_PAGE_ID",
+            AppSecret:       "YOUR_APP_SECRET_USED_FOR_VERIFYING_REQUESTS_SIGNED_BY_FACEBOOK",
             MessageReceived: messageReceived, // your function for handling received messages, defined below
         }
 
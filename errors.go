@@ -0,0 +1,69 @@
+package messenger
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Sentinel errors for matching FacebookError with errors.Is
+var (
+	// ErrUserUnavailable is returned if user blocked the page, deleted the conversation or is otherwise unavailable
+	ErrUserUnavailable = errors.New("FB Error: user is unavailable")
+
+	// ErrRateLimited is returned if application or page hit Graph API rate limit
+	ErrRateLimited = errors.New("FB Error: rate limited")
+
+	// ErrTokenExpired is returned if access token is expired or invalidated
+	ErrTokenExpired = errors.New("FB Error: access token expired")
+
+	// ErrOutsideWindow is returned if message is sent outside of 24 hours messaging window without allowed message tag
+	ErrOutsideWindow = errors.New("FB Error: message sent outside of allowed window")
+)
+
+// Graph API error codes and subcodes
+const (
+	codeUnknown            = 1
+	codeServiceUnavailable = 2
+	codeAppRateLimit       = 4
+	codeUserRateLimit      = 17
+	codePageRateLimit      = 32
+	codeAccessToken        = 190
+	codeUserUnavailable    = 551
+	codeCallRateLimit      = 613
+
+	subcodeSendRateLimit   = 2018022
+	subcodeOutsideWindow   = 2018278
+	subcodeUserUnavailable = 1545041
+)
+
+// Is reports whether FacebookError matches target sentinel error, so errors.Is(err, ErrRateLimited) can be used
+func (err *FacebookError) Is(target error) bool {
+	switch target {
+	case ErrUserUnavailable:
+		return err.Code == codeUserUnavailable || err.ErrorSubcode == subcodeUserUnavailable
+	case ErrRateLimited:
+		switch err.Code {
+		case codeAppRateLimit, codeUserRateLimit, codePageRateLimit, codeCallRateLimit:
+			return true
+		}
+		return err.ErrorSubcode == subcodeSendRateLimit
+	case ErrTokenExpired:
+		return err.Code == codeAccessToken
+	case ErrOutsideWindow:
+		return err.ErrorSubcode == subcodeOutsideWindow
+	}
+	return false
+}
+
+// IsRetryable returns true if the same request can succeed if it is sent again later,
+// like for rate limiting, temporary Facebook errors or 5xx HTTP responses
+func (err *FacebookError) IsRetryable() bool {
+	if err.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+	switch err.Code {
+	case codeUnknown, codeServiceUnavailable:
+		return true
+	}
+	return err.Is(ErrRateLimited)
+}
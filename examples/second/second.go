@@ -11,6 +11,7 @@ import (
 var msng = &messenger.Messenger{
 	AccessToken: "YOUR_ACCESS_TOKEN_THAT_YOU_WILL_GENERATE_FOR_YOUR_PAGE_ON_FACEBOOK",
 	PageID:      "YOUR_PAGE_ID",
+	AppSecret:   "YOUR_APP_SECRET_FROM_FACEBOOK_APP_DASHBOARD",
 }
 
 // Please check the First example First, it contains more example code for sending messages
@@ -26,7 +27,17 @@ func main() {
 // myHandler is you regular http Handler
 func myHandler(w http.ResponseWriter, r *http.Request) {
 
-	msng.VerifyWebhook(w, r)                   // verify webhook if asked from Facebook
+	if r.Method == http.MethodGet {
+		msng.VerifyWebhook(w, r) // verify webhook if asked from Facebook
+		return
+	}
+
+	// check that request is really sent by Facebook and signed with your app secret
+	if _, err := messenger.VerifySignature(r, msng.AppSecret); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	fbRequest, _ := messenger.DecodeRequest(r) // decode entire request received from Facebook into FacebookRequest struct
 
 	// now you have it all and you can do whatever you want with received request
@@ -1,33 +1,152 @@
 package messenger
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // FacebookRequest received from Facebook server on webhook, contains messages, delivery reports and/or postbacks
 type FacebookRequest struct {
 	Entry []struct {
-		ID        int64 `json:"id"`
-		Messaging []struct {
-			Recipient struct {
-				ID int64 `json:"id,string"`
-			} `json:"recipient"`
-			Sender struct {
-				ID int64 `json:"id,string"`
-			} `json:"sender"`
-			Timestamp int               `json:"timestamp"`
-			Message   *FacebookMessage  `json:"message,omitempty"`
-			Delivery  *FacebookDelivery `json:"delivery"`
-			Postback  *FacebookPostback `json:"postback"`
-		} `json:"messaging"`
-		Time int `json:"time"`
+		ID        int64               `json:"id"`
+		Messaging []FacebookMessaging `json:"messaging"`
+		Standby   []FacebookMessaging `json:"standby"`
+		Changes   []FacebookChange    `json:"changes"`
+		Time      int                 `json:"time"`
 	} `json:"entry"`
 	Object string `json:"object"`
 }
 
+// FacebookMessaging is single event received as part of FacebookRequest entry
+// Events in entry Standby are received when your app is not the thread owner
+// Only one of message, delivery, postback, read, referral, optin, reaction or handover fields is set
+type FacebookMessaging struct {
+	Recipient struct {
+		ID int64 `json:"id,string"`
+	} `json:"recipient"`
+	Sender struct {
+		ID int64 `json:"id,string"`
+	} `json:"sender"`
+	Timestamp int               `json:"timestamp"`
+	Message   *FacebookMessage  `json:"message,omitempty"`
+	Delivery  *FacebookDelivery `json:"delivery"`
+	Postback  *FacebookPostback `json:"postback"`
+	Read      *FacebookRead     `json:"read,omitempty"`
+	Referral  *FacebookReferral `json:"referral,omitempty"`
+	Optin     *FacebookOptin    `json:"optin,omitempty"`
+	Reaction  *FacebookReaction `json:"reaction,omitempty"`
+
+	PassThreadControl    *FacebookPassThreadControl    `json:"pass_thread_control,omitempty"`
+	TakeThreadControl    *FacebookTakeThreadControl    `json:"take_thread_control,omitempty"`
+	RequestThreadControl *FacebookRequestThreadControl `json:"request_thread_control,omitempty"`
+	AppRoles             FacebookAppRoles              `json:"app_roles,omitempty"`
+}
+
+// FacebookChange is page change received as part of FacebookRequest entry, like new comment for feed field
+type FacebookChange struct {
+	Field string             `json:"field"`
+	Value FacebookFeedChange `json:"value"`
+}
+
+// FacebookFeedChange struct for changes of page feed received as part of FacebookChange
+// Item is "comment" for comments, Verb can be "add", "edited" or "remove"
+type FacebookFeedChange struct {
+	Item        string        `json:"item"`
+	Verb        string        `json:"verb"`
+	PostID      string        `json:"post_id"`
+	CommentID   string        `json:"comment_id,omitempty"`
+	ParentID    string        `json:"parent_id,omitempty"`
+	Message     string        `json:"message,omitempty"`
+	CreatedTime int64         `json:"created_time"`
+	From        *FacebookFrom `json:"from,omitempty"`
+}
+
+// FacebookFrom is author of feed change
+type FacebookFrom struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 // FacebookMessage struct for text messaged received from facebook server as part of FacebookRequest struct
 type FacebookMessage struct {
 	Mid  string `json:"mid"`
 	Seq  int    `json:"seq"`
 	Text string `json:"text"`
+
+	// QuickReply is set if user tapped quick reply button instead of typing the text, nil otherwise
+	QuickReply *FacebookQuickReply `json:"quick_reply,omitempty"`
+
+	// ReplyTo is set if user replied to specific message, Mid is ID of that message
+	ReplyTo *FacebookReplyTo `json:"reply_to,omitempty"`
+
+	// Attachments sent by user, like images, stickers, location or files, Text is empty in that case
+	Attachments []FacebookAttachment `json:"attachments,omitempty"`
+
+	// IsEcho is true for messages sent by your page, AppID is ID of app that sent the message
+	// and Metadata is custom string set when message was sent
+	IsEcho   bool   `json:"is_echo,omitempty"`
+	AppID    int64  `json:"app_id,omitempty"`
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// likeStickerIDs are IDs of small, medium and large thumbs-up sticker
+var likeStickerIDs = []int64{369239263222822, 369239343222814, 369239383222810}
+
+// FacebookAttachment struct for attachments received from Facebook server as part of FacebookMessage
+// Title and URL are set for fallback attachments (shared links), Payload is set for other types
+type FacebookAttachment struct {
+	Type    AttachmentType            `json:"type"`
+	Payload FacebookAttachmentPayload `json:"payload"`
+	Title   string                    `json:"title,omitempty"`
+	URL     string                    `json:"url,omitempty"`
+}
+
+// FacebookAttachmentPayload struct for attachment payload received as part of FacebookAttachment
+// StickerID is set only for stickers and Coordinates only for location attachments
+type FacebookAttachmentPayload struct {
+	URL         string               `json:"url,omitempty"`
+	StickerID   int64                `json:"sticker_id,omitempty"`
+	Coordinates *FacebookCoordinates `json:"coordinates,omitempty"`
+}
+
+// FacebookCoordinates of location shared by user
+type FacebookCoordinates struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
+}
+
+// IsSticker returns true if attachment is a sticker
+func (a FacebookAttachment) IsSticker() bool {
+	return a.Payload.StickerID != 0
+}
+
+// IsLike returns true if attachment is thumbs-up (like) sticker
+func (a FacebookAttachment) IsLike() bool {
+	for _, id := range likeStickerIDs {
+		if a.Payload.StickerID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// FacebookQuickReply contains payload of quick reply that user tapped, received as part of FacebookMessage
+type FacebookQuickReply struct {
+	Payload string `json:"payload"`
+}
+
+// FacebookReplyTo contains ID of message user replied to, received as part of FacebookMessage
+type FacebookReplyTo struct {
+	Mid string `json:"mid"`
+}
+
+// FacebookReaction struct for reactions to messages received from Facebook server as part of FacebookRequest struct
+// Action is "react" or "unreact", Reaction is name like "love" or "smile" and Emoji is the emoji itself
+type FacebookReaction struct {
+	Reaction string `json:"reaction,omitempty"`
+	Emoji    string `json:"emoji,omitempty"`
+	Action   string `json:"action"`
+	Mid      string `json:"mid"`
 }
 
 // FacebookDelivery struct for delivery reports received from Facebook server as part of FacebookRequest struct
@@ -37,34 +156,88 @@ type FacebookDelivery struct {
 	Watermark int      `json:"watermark"`
 }
 
+// FacebookRead struct for read receipts received from Facebook server as part of FacebookRequest struct
+// All messages sent before Watermark timestamp were read by user
+type FacebookRead struct {
+	Watermark int `json:"watermark"`
+	Seq       int `json:"seq"`
+}
+
 // FacebookPostback struct for postbacks received from Facebook server  as part of FacebookRequest struct
 type FacebookPostback struct {
+	Title   string `json:"title,omitempty"`
 	Payload string `json:"payload"`
+
+	// Referral is set if user pressed Get Started button after arriving from m.me link, ad or plugin
+	Referral *FacebookReferral `json:"referral,omitempty"`
+}
+
+// FacebookReferral struct for referrals received when user arrives to conversation from m.me link,
+// Chat plugin or ad, as part of FacebookRequest or FacebookPostback struct
+type FacebookReferral struct {
+	Ref        string `json:"ref,omitempty"`
+	Source     string `json:"source"`
+	Type       string `json:"type"`
+	AdID       string `json:"ad_id,omitempty"`
+	RefererURI string `json:"referer_uri,omitempty"`
 }
 
-// rawFBResponse received from Facebook server after sending the message
-// if Error is null we copy this into FacebookResponse object
-type rawFBResponse struct {
-	MessageID   string         `json:"message_id"`
-	RecipientID int64          `json:"recipient_id,string"`
-	Error       *FacebookError `json:"error"`
+// FacebookOptin struct for opt-ins received from Send to Messenger and Checkbox plugins as part of FacebookRequest struct
+// UserRef is set for Checkbox plugin, use it as recipient since sender ID is not known
+type FacebookOptin struct {
+	Ref     string `json:"ref,omitempty"`
+	UserRef string `json:"user_ref,omitempty"`
 }
 
 // FacebookResponse received from Facebook server after sending the message
+// AttachmentID is set only if reusable attachment was sent
 type FacebookResponse struct {
-	MessageID   string `json:"message_id"`
-	RecipientID int64  `json:"recipient_id,string"`
+	MessageID    string `json:"message_id"`
+	RecipientID  int64  `json:"recipient_id,string"`
+	AttachmentID string `json:"attachment_id,omitempty"`
+}
+
+// FacebookPassThreadControl received when another app passed thread control to your app
+type FacebookPassThreadControl struct {
+	NewOwnerAppID      int64  `json:"new_owner_app_id,string"`
+	PreviousOwnerAppID int64  `json:"previous_owner_app_id,string,omitempty"`
+	Metadata           string `json:"metadata"`
 }
 
-// FacebookError received form Facebook server if sending messages failed
+// FacebookTakeThreadControl received when primary receiver took thread control from your app
+type FacebookTakeThreadControl struct {
+	PreviousOwnerAppID int64  `json:"previous_owner_app_id,string"`
+	NewOwnerAppID      int64  `json:"new_owner_app_id,string,omitempty"`
+	Metadata           string `json:"metadata"`
+}
+
+// FacebookRequestThreadControl received by primary receiver when secondary receiver requests thread control
+// Unlike other handover events, Facebook sends RequestedOwnerAppID as number, not string
+type FacebookRequestThreadControl struct {
+	RequestedOwnerAppID int64  `json:"requested_owner_app_id"`
+	Metadata            string `json:"metadata"`
+}
+
+// FacebookAppRoles received when page admin changes roles of apps, maps app ID to its roles
+// like "primary_receiver" or "secondary_receiver"
+type FacebookAppRoles map[string][]string
+
+// FacebookError received form Facebook server if sending messages or other Graph API call failed
+// It can be matched with errors.Is against ErrUserUnavailable, ErrRateLimited, ErrTokenExpired and ErrOutsideWindow
+// StatusCode is HTTP status code of Facebook response and RetryAfter is time Facebook asked to wait before next call
 type FacebookError struct {
-	Code      int    `json:"code"`
-	FbtraceID string `json:"fbtrace_id"`
-	Message   string `json:"message"`
-	Type      string `json:"type"`
+	Code           int           `json:"code"`
+	ErrorSubcode   int           `json:"error_subcode,omitempty"`
+	ErrorUserTitle string        `json:"error_user_title,omitempty"`
+	ErrorUserMsg   string        `json:"error_user_msg,omitempty"`
+	FbtraceID      string        `json:"fbtrace_id"`
+	Message        string        `json:"message"`
+	Type           string        `json:"type"`
+	StatusCode     int           `json:"-"`
+	RetryAfter     time.Duration `json:"-"`
 }
 
-// Error returns Go error object constructed from FacebookError data
-func (err *FacebookError) Error() error {
-	return fmt.Errorf("FB Error: Type %s: %s; FB trace ID: %s", err.Type, err.Message, err.FbtraceID)
+// Error returns error text constructed from FacebookError data
+func (err *FacebookError) Error() string {
+	return fmt.Sprintf("FB Error: Type %s: %s (code %d, subcode %d); FB trace ID: %s", err.Type, err.Message, err.Code, err.ErrorSubcode, err.FbtraceID)
 }
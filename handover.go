@@ -0,0 +1,92 @@
+package messenger
+
+import (
+	"context"
+	"strconv"
+)
+
+// PageInboxAppID is app ID of Page Inbox, use it as target app when passing thread control to human agents
+const PageInboxAppID = 263902037430900
+
+// SecondaryReceiver is app that can receive thread control from primary receiver
+type SecondaryReceiver struct {
+	ID   int64  `json:"id,string"`
+	Name string `json:"name"`
+}
+
+type handoverRequest struct {
+	Recipient   Recipient `json:"recipient"`
+	TargetAppID int64     `json:"target_app_id,omitempty"`
+	Metadata    string    `json:"metadata,omitempty"`
+}
+
+type threadOwnerResponse struct {
+	Data []struct {
+		ThreadOwner struct {
+			AppID int64 `json:"app_id,string"`
+		} `json:"thread_owner"`
+	} `json:"data"`
+}
+
+type secondaryReceiversResponse struct {
+	Data []SecondaryReceiver `json:"data"`
+}
+
+// PassThreadControl passes conversation with userID to app targetAppID, like PageInboxAppID
+// metadata is optional and is sent to target app with pass thread control event
+func (msng *Messenger) PassThreadControl(ctx context.Context, userID, targetAppID int64, metadata string) error {
+	return msng.handover(ctx, "me/pass_thread_control", handoverRequest{
+		Recipient:   Recipient{ID: userID},
+		TargetAppID: targetAppID,
+		Metadata:    metadata,
+	})
+}
+
+// TakeThreadControl takes conversation with userID from current thread owner, only primary receiver can take control
+func (msng *Messenger) TakeThreadControl(ctx context.Context, userID int64, metadata string) error {
+	return msng.handover(ctx, "me/take_thread_control", handoverRequest{
+		Recipient: Recipient{ID: userID},
+		Metadata:  metadata,
+	})
+}
+
+// RequestThreadControl asks primary receiver to pass conversation with userID to your app
+func (msng *Messenger) RequestThreadControl(ctx context.Context, userID int64, metadata string) error {
+	return msng.handover(ctx, "me/request_thread_control", handoverRequest{
+		Recipient: Recipient{ID: userID},
+		Metadata:  metadata,
+	})
+}
+
+// ReleaseThreadControl releases conversation with userID back to primary receiver
+func (msng *Messenger) ReleaseThreadControl(ctx context.Context, userID int64, metadata string) error {
+	return msng.handover(ctx, "me/release_thread_control", handoverRequest{
+		Recipient: Recipient{ID: userID},
+		Metadata:  metadata,
+	})
+}
+
+// GetThreadOwner returns app ID of current owner of conversation with userID
+func (msng *Messenger) GetThreadOwner(ctx context.Context, userID int64) (int64, error) {
+	var resp threadOwnerResponse
+	if err := msng.callJSON(ctx, "GET", "me/thread_owner?recipient="+strconv.FormatInt(userID, 10), nil, &resp); err != nil {
+		return 0, err
+	}
+	if len(resp.Data) == 0 {
+		return 0, nil
+	}
+	return resp.Data[0].ThreadOwner.AppID, nil
+}
+
+// GetSecondaryReceivers returns apps set as secondary receivers for your page, only primary receiver can call it
+func (msng *Messenger) GetSecondaryReceivers(ctx context.Context) ([]SecondaryReceiver, error) {
+	var resp secondaryReceiversResponse
+	if err := msng.callJSON(ctx, "GET", "me/secondary_receivers?fields=id,name", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (msng *Messenger) handover(ctx context.Context, path string, r handoverRequest) error {
+	return msng.callJSON(ctx, "POST", path, r, nil)
+}
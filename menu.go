@@ -0,0 +1,122 @@
+package messenger
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// ButtonTypeNested is type for persistent menu items that open submenu
+const ButtonTypeNested = ButtonType("nested")
+
+// PersistentMenu shown in Messenger for users with locale, use DefaultLocale for menu shown to all other users
+type PersistentMenu struct {
+	Locale                string     `json:"locale"`
+	ComposerInputDisabled bool       `json:"composer_input_disabled"`
+	CallToActions         []MenuItem `json:"call_to_actions,omitempty"`
+}
+
+// MenuItem of persistent menu, it can be postback, web URL or nested item with submenu in CallToActions
+type MenuItem struct {
+	Type               ButtonType `json:"type"`
+	Title              string     `json:"title"`
+	URL                string     `json:"url,omitempty"`
+	Payload            string     `json:"payload,omitempty"`
+	WebviewHeightRatio string     `json:"webview_height_ratio,omitempty"`
+	CallToActions      []MenuItem `json:"call_to_actions,omitempty"`
+}
+
+type userSettings struct {
+	PSID           int64            `json:"psid,string"`
+	PersistentMenu []PersistentMenu `json:"persistent_menu"`
+}
+
+type userSettingsResponse struct {
+	Data []struct {
+		UserLevelPersistentMenu []PersistentMenu `json:"user_level_persistent_menu"`
+	} `json:"data"`
+}
+
+// NewPersistentMenu creates new persistent menu for locale, use DefaultLocale for menu shown to all users
+func (msng Messenger) NewPersistentMenu(locale string, composerInputDisabled bool) PersistentMenu {
+	return PersistentMenu{
+		Locale:                locale,
+		ComposerInputDisabled: composerInputDisabled,
+	}
+}
+
+// AddPostbackItem adds menu item that sends payload string back to webhook when pressed
+func (pm *PersistentMenu) AddPostbackItem(title, payload string) {
+	pm.CallToActions = append(pm.CallToActions, newMenuItem(ButtonTypePostback, title, "", payload))
+}
+
+// AddWebURLItem adds menu item that opens URL when pressed
+func (pm *PersistentMenu) AddWebURLItem(title, URL string) {
+	pm.CallToActions = append(pm.CallToActions, newMenuItem(ButtonTypeWebURL, title, URL, ""))
+}
+
+// AddNestedItem adds menu item that opens submenu with items
+func (pm *PersistentMenu) AddNestedItem(title string, items []MenuItem) {
+	mi := newMenuItem(ButtonTypeNested, title, "", "")
+	mi.CallToActions = items
+	pm.CallToActions = append(pm.CallToActions, mi)
+}
+
+// AddPostbackItem adds postback item to nested menu item
+func (mi *MenuItem) AddPostbackItem(title, payload string) {
+	mi.CallToActions = append(mi.CallToActions, newMenuItem(ButtonTypePostback, title, "", payload))
+}
+
+// AddWebURLItem adds web URL item to nested menu item
+func (mi *MenuItem) AddWebURLItem(title, URL string) {
+	mi.CallToActions = append(mi.CallToActions, newMenuItem(ButtonTypeWebURL, title, URL, ""))
+}
+
+func newMenuItem(t ButtonType, title, URL, payload string) MenuItem {
+	return MenuItem{
+		Type:    t,
+		Title:   title,
+		URL:     URL,
+		Payload: payload,
+	}
+}
+
+// SetPersistentMenu sets page-wide persistent menu, one menu for each locale
+func (msng *Messenger) SetPersistentMenu(ctx context.Context, menus ...PersistentMenu) error {
+	return msng.SetMessengerProfile(ctx, MessengerProfile{PersistentMenu: menus})
+}
+
+// DeletePersistentMenu removes page-wide persistent menu
+func (msng *Messenger) DeletePersistentMenu(ctx context.Context) error {
+	return msng.DeleteMessengerProfile(ctx, ProfileFieldPersistentMenu)
+}
+
+// SetUserPersistentMenu sets persistent menu shown only to user psid instead of page-wide menu
+func (msng *Messenger) SetUserPersistentMenu(ctx context.Context, psid int64, menus ...PersistentMenu) error {
+	s := userSettings{
+		PSID:           psid,
+		PersistentMenu: menus,
+	}
+	return msng.callJSON(ctx, "POST", "me/custom_user_settings", s, nil)
+}
+
+// GetUserPersistentMenu returns persistent menu set for user psid, it is empty if user sees page-wide menu
+func (msng *Messenger) GetUserPersistentMenu(ctx context.Context, psid int64) ([]PersistentMenu, error) {
+	var resp userSettingsResponse
+	if err := msng.callJSON(ctx, "GET", "me/custom_user_settings?psid="+strconv.FormatInt(psid, 10), nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	return resp.Data[0].UserLevelPersistentMenu, nil
+}
+
+// DeleteUserPersistentMenu resets persistent menu of user psid back to page-wide menu
+func (msng *Messenger) DeleteUserPersistentMenu(ctx context.Context, psid int64) error {
+	q := url.Values{
+		"psid":   {strconv.FormatInt(psid, 10)},
+		"params": {`["persistent_menu"]`},
+	}
+	return msng.callJSON(ctx, "DELETE", "me/custom_user_settings?"+q.Encode(), nil, nil)
+}
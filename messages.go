@@ -1,5 +1,19 @@
 package messenger
 
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// maxButtonTemplateText is maximum number of characters in button template text
+	maxButtonTemplateText = 640
+
+	// maxButtonTemplateButtons is maximum number of buttons in button template
+	maxButtonTemplateButtons = 3
+)
+
 // ButtonType for buttons, it can be ButtonTypeWebURL or ButtonTypePostback
 type ButtonType string
 
@@ -12,13 +26,35 @@ type TemplateType string
 // NotificationType for sent messages
 type NotificationType string
 
+// MessagingType of sent message, it can be MessagingTypeResponse, MessagingTypeUpdate or MessagingTypeMessageTag
+type MessagingType string
+
+// MessageTag allows sending message outside of 24 hours messaging window, it requires MessagingTypeMessageTag
+type MessageTag string
+
+// SenderAction for typing indicators and read receipts, it can be SenderActionTypingOn, SenderActionTypingOff or SenderActionMarkSeen
+type SenderAction string
+
+// QuickReplyContentType of quick reply, it can be text, location, user email or user phone number
+type QuickReplyContentType string
+
 // Message interface that represents all type of messages that we can send to Facebook Messenger
 type Message interface {
 	foo()
 }
 
-func (m TextMessage) foo()    {} // Message interface
-func (m GenericMessage) foo() {} // Message interface
+// validator is implemented by messages that check Facebook limits before sending
+type validator interface {
+	Validate() error
+}
+
+func (m TextMessage) foo()           {} // Message interface
+func (m GenericMessage) foo()        {} // Message interface
+func (m SenderActionMessage) foo()   {} // Message interface
+func (m AttachmentMessage) foo()     {} // Message interface
+func (m ButtonTemplateMessage) foo() {} // Message interface
+func (m ReceiptMessage) foo()        {} // Message interface
+func (m MediaTemplateMessage) foo()  {} // Message interface
 
 const (
 	// ButtonTypeWebURL is type for web links
@@ -30,9 +66,36 @@ const (
 	// AttachmentTypeTemplate for template attachments
 	AttachmentTypeTemplate = AttachmentType("template")
 
+	// AttachmentTypeImage for image attachments
+	AttachmentTypeImage = AttachmentType("image")
+
+	// AttachmentTypeAudio for audio attachments
+	AttachmentTypeAudio = AttachmentType("audio")
+
+	// AttachmentTypeVideo for video attachments
+	AttachmentTypeVideo = AttachmentType("video")
+
+	// AttachmentTypeFile for file attachments
+	AttachmentTypeFile = AttachmentType("file")
+
+	// AttachmentTypeLocation for location attachments received from user
+	AttachmentTypeLocation = AttachmentType("location")
+
+	// AttachmentTypeFallback for shared links and other attachments received from user that don't have specific type
+	AttachmentTypeFallback = AttachmentType("fallback")
+
 	// TemplateTypeGeneric for generic message templates
 	TemplateTypeGeneric = TemplateType("generic")
 
+	// TemplateTypeButton for button message templates
+	TemplateTypeButton = TemplateType("button")
+
+	// TemplateTypeReceipt for receipt message templates
+	TemplateTypeReceipt = TemplateType("receipt")
+
+	// TemplateTypeMedia for media message templates
+	TemplateTypeMedia = TemplateType("media")
+
 	// NotificationTypeRegular for regular notification type
 	NotificationTypeRegular = NotificationType("REGULAR")
 
@@ -41,28 +104,141 @@ const (
 
 	// NotificationTypeNoPush for no push
 	NotificationTypeNoPush = NotificationType("NO_PUSH")
+
+	// MessagingTypeResponse for messages sent in response to received message, default for all messages
+	MessagingTypeResponse = MessagingType("RESPONSE")
+
+	// MessagingTypeUpdate for messages sent proactively, not in response to received message
+	MessagingTypeUpdate = MessagingType("UPDATE")
+
+	// MessagingTypeMessageTag for messages sent outside of 24 hours messaging window with Tag set
+	MessagingTypeMessageTag = MessagingType("MESSAGE_TAG")
+
+	// MessageTagConfirmedEventUpdate for reminders and updates of event user has registered for
+	MessageTagConfirmedEventUpdate = MessageTag("CONFIRMED_EVENT_UPDATE")
+
+	// MessageTagPostPurchaseUpdate for updates about user's recent purchase
+	MessageTagPostPurchaseUpdate = MessageTag("POST_PURCHASE_UPDATE")
+
+	// MessageTagAccountUpdate for non-recurring updates of user's account or application
+	MessageTagAccountUpdate = MessageTag("ACCOUNT_UPDATE")
+
+	// MessageTagHumanAgent for human agent responses within 7 days after user's message
+	MessageTagHumanAgent = MessageTag("HUMAN_AGENT")
+
+	// SenderActionTypingOn turns typing indicator on, Facebook turns it off after 20 seconds or when message is sent
+	SenderActionTypingOn = SenderAction("typing_on")
+
+	// SenderActionTypingOff turns typing indicator off
+	SenderActionTypingOff = SenderAction("typing_off")
+
+	// SenderActionMarkSeen marks last message from user as read
+	SenderActionMarkSeen = SenderAction("mark_seen")
+
+	// QuickReplyContentTypeText for quick reply with title, payload and optional image
+	QuickReplyContentTypeText = QuickReplyContentType("text")
+
+	// QuickReplyContentTypeLocation for quick reply that asks user to send his location
+	QuickReplyContentTypeLocation = QuickReplyContentType("location")
+
+	// QuickReplyContentTypeUserEmail for quick reply prefilled with user's email address
+	QuickReplyContentTypeUserEmail = QuickReplyContentType("user_email")
+
+	// QuickReplyContentTypeUserPhoneNumber for quick reply prefilled with user's phone number
+	QuickReplyContentTypeUserPhoneNumber = QuickReplyContentType("user_phone_number")
 )
 
 // TextMessage struct used for sending text messages to messenger
 type TextMessage struct {
 	Message          textMessageContent `json:"message"`
-	Recipient        recipient          `json:"recipient"`
+	Recipient        Recipient          `json:"recipient"`
 	NotificationType NotificationType   `json:"notification_type,omitempty"`
+	MessagingType    MessagingType      `json:"messaging_type,omitempty"`
+	Tag              MessageTag         `json:"tag,omitempty"`
 }
 
 // GenericMessage struct used for sending structural messages to messenger (messages with images, links, and buttons)
 type GenericMessage struct {
 	Message          genericMessageContent `json:"message"`
-	Recipient        recipient             `json:"recipient"`
+	Recipient        Recipient             `json:"recipient"`
+	NotificationType NotificationType      `json:"notification_type,omitempty"`
+	MessagingType    MessagingType         `json:"messaging_type,omitempty"`
+	Tag              MessageTag            `json:"tag,omitempty"`
+}
+
+// MediaTemplateMessage struct used for sending single image or video with buttons to messenger
+// Messenger Profile can't hold messages, so it can't be set as welcome message. To greet new users with it,
+// use SetGetStarted and send the message when Get Started postback is received
+type MediaTemplateMessage struct {
+	Message          mediaTemplateContent `json:"message"`
+	Recipient        Recipient            `json:"recipient"`
+	NotificationType NotificationType     `json:"notification_type,omitempty"`
+	MessagingType    MessagingType        `json:"messaging_type,omitempty"`
+	Tag              MessageTag           `json:"tag,omitempty"`
+}
+
+// ButtonTemplateMessage struct used for sending text with up to 3 buttons to messenger
+type ButtonTemplateMessage struct {
+	Message          buttonTemplateContent `json:"message"`
+	Recipient        Recipient             `json:"recipient"`
+	NotificationType NotificationType      `json:"notification_type,omitempty"`
+	MessagingType    MessagingType         `json:"messaging_type,omitempty"`
+	Tag              MessageTag            `json:"tag,omitempty"`
+}
+
+// AttachmentMessage struct used for sending image, audio, video and file messages to messenger
+type AttachmentMessage struct {
+	Message          genericMessageContent `json:"message"`
+	Recipient        Recipient             `json:"recipient"`
 	NotificationType NotificationType      `json:"notification_type,omitempty"`
+	MessagingType    MessagingType         `json:"messaging_type,omitempty"`
+	Tag              MessageTag            `json:"tag,omitempty"`
+}
+
+// SenderActionMessage struct used for sending typing indicators and mark seen actions to messenger
+type SenderActionMessage struct {
+	Recipient    Recipient    `json:"recipient"`
+	SenderAction SenderAction `json:"sender_action"`
 }
 
-type recipient struct {
-	ID int64 `json:"id,string"`
+// Recipient of sent message, only one of ID, UserRef, PhoneNumber, PostID or CommentID should be set
+// ID is user's page-scoped ID, UserRef is received from Checkbox plugin opt-in, PhoneNumber requires
+// pages_messaging_phone_number permission and Name can be set with it, PostID and CommentID are used for private replies
+type Recipient struct {
+	ID          int64          `json:"id,string,omitempty"`
+	UserRef     string         `json:"user_ref,omitempty"`
+	PhoneNumber string         `json:"phone_number,omitempty"`
+	Name        *RecipientName `json:"name,omitempty"`
+	PostID      string         `json:"post_id,omitempty"`
+	CommentID   string         `json:"comment_id,omitempty"`
+}
+
+// key returns string identifying recipient, used for per-recipient rate limiting and ordering
+func (r Recipient) key() string {
+	switch {
+	case r.ID != 0:
+		return strconv.FormatInt(r.ID, 10)
+	case r.UserRef != "":
+		return "user_ref:" + r.UserRef
+	case r.PhoneNumber != "":
+		return "phone_number:" + r.PhoneNumber
+	case r.CommentID != "":
+		return "comment_id:" + r.CommentID
+	case r.PostID != "":
+		return "post_id:" + r.PostID
+	}
+	return ""
+}
+
+// RecipientName of recipient addressed by phone number, used for matching the Facebook account
+type RecipientName struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
 }
 
 type textMessageContent struct {
-	Text string `json:"text,omitempty"`
+	Text         string       `json:"text,omitempty"`
+	QuickReplies []QuickReply `json:"quick_replies,omitempty"`
 }
 
 type genericMessageContent struct {
@@ -77,6 +253,46 @@ type attachment struct {
 type payload struct {
 	TemplateType string    `json:"template_type,omitempty"`
 	Elements     []Element `json:"elements,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	IsReusable   bool      `json:"is_reusable,omitempty"`
+	AttachmentID string    `json:"attachment_id,omitempty"`
+}
+
+type buttonTemplateContent struct {
+	Attachment buttonTemplateAttachment `json:"attachment"`
+}
+
+type buttonTemplateAttachment struct {
+	Type    string                `json:"type"`
+	Payload buttonTemplatePayload `json:"payload"`
+}
+
+type buttonTemplatePayload struct {
+	TemplateType string   `json:"template_type"`
+	Text         string   `json:"text"`
+	Buttons      []Button `json:"buttons"`
+}
+
+type mediaTemplateContent struct {
+	Attachment mediaTemplateAttachment `json:"attachment"`
+}
+
+type mediaTemplateAttachment struct {
+	Type    string               `json:"type"`
+	Payload mediaTemplatePayload `json:"payload"`
+}
+
+type mediaTemplatePayload struct {
+	TemplateType string         `json:"template_type"`
+	Elements     []MediaElement `json:"elements"`
+}
+
+// MediaElement in Media Template message, it holds image or video set by Facebook URL or attachment ID
+type MediaElement struct {
+	MediaType    AttachmentType `json:"media_type"`
+	URL          string         `json:"url,omitempty"`
+	AttachmentID string         `json:"attachment_id,omitempty"`
+	Buttons      []Button       `json:"buttons,omitempty"`
 }
 
 // Element in Generic Message template attachment
@@ -96,21 +312,55 @@ type Button struct {
 	Payload string     `json:"payload,omitempty"`
 }
 
+// QuickReply button shown above the composer, Messenger allows up to 13 quick replies per message
+type QuickReply struct {
+	ContentType QuickReplyContentType `json:"content_type"`
+	Title       string                `json:"title,omitempty"`
+	Payload     string                `json:"payload,omitempty"`
+	ImageURL    string                `json:"image_url,omitempty"`
+}
+
 // NewTextMessage creates new text message for userID
 // This function is here for convenient reason, you will
 // probably use shorthand version SentTextMessage which sends message immediatly
 func (msng Messenger) NewTextMessage(userID int64, text string) TextMessage {
+	return msng.NewTextMessageTo(Recipient{ID: userID}, text)
+}
+
+// NewTextMessageTo creates new text message for recipient r, use it for user_ref, phone number or comment recipients
+func (msng Messenger) NewTextMessageTo(r Recipient, text string) TextMessage {
 	return TextMessage{
-		Recipient: recipient{ID: userID},
-		Message:   textMessageContent{Text: text},
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
+		Message:       textMessageContent{Text: text},
+	}
+}
+
+// NewSenderActionMessage creates new sender action message for userID
+// You will probably use shorthand version SendAction which sends action immediately
+func (msng Messenger) NewSenderActionMessage(userID int64, action SenderAction) SenderActionMessage {
+	return msng.NewSenderActionMessageTo(Recipient{ID: userID}, action)
+}
+
+// NewSenderActionMessageTo creates new sender action message for recipient r
+func (msng Messenger) NewSenderActionMessageTo(r Recipient, action SenderAction) SenderActionMessage {
+	return SenderActionMessage{
+		Recipient:    r,
+		SenderAction: action,
 	}
 }
 
 // NewGenericMessage creates new Generic Template message for userID
 // Generic template messages are used for structured messages with images, links, buttons and postbacks
 func (msng Messenger) NewGenericMessage(userID int64) GenericMessage {
+	return msng.NewGenericMessageTo(Recipient{ID: userID})
+}
+
+// NewGenericMessageTo creates new Generic Template message for recipient r
+func (msng Messenger) NewGenericMessageTo(r Recipient) GenericMessage {
 	return GenericMessage{
-		Recipient: recipient{ID: userID},
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
 		Message: genericMessageContent{
 			Attachment: &attachment{
 				Type:    "template",
@@ -120,6 +370,210 @@ func (msng Messenger) NewGenericMessage(userID int64) GenericMessage {
 	}
 }
 
+// NewMediaTemplateMessage creates new Media Template message for userID with image or video from Facebook URL
+// mediaType can be AttachmentTypeImage or AttachmentTypeVideo, URL must be Facebook URL of the media, not external one
+func (msng Messenger) NewMediaTemplateMessage(userID int64, mediaType AttachmentType, URL string) MediaTemplateMessage {
+	return msng.NewMediaTemplateMessageTo(Recipient{ID: userID}, mediaType, URL)
+}
+
+// NewMediaTemplateMessageTo creates new Media Template message for recipient r with image or video from Facebook URL
+func (msng Messenger) NewMediaTemplateMessageTo(r Recipient, mediaType AttachmentType, URL string) MediaTemplateMessage {
+	return newMediaTemplateMessage(r, MediaElement{MediaType: mediaType, URL: URL})
+}
+
+// NewMediaTemplateMessageByID creates new Media Template message for userID with previously uploaded image or video
+// mediaType can be AttachmentTypeImage or AttachmentTypeVideo
+func (msng Messenger) NewMediaTemplateMessageByID(userID int64, mediaType AttachmentType, attachmentID string) MediaTemplateMessage {
+	return msng.NewMediaTemplateMessageByIDTo(Recipient{ID: userID}, mediaType, attachmentID)
+}
+
+// NewMediaTemplateMessageByIDTo creates new Media Template message for recipient r with previously uploaded image or video
+func (msng Messenger) NewMediaTemplateMessageByIDTo(r Recipient, mediaType AttachmentType, attachmentID string) MediaTemplateMessage {
+	return newMediaTemplateMessage(r, MediaElement{MediaType: mediaType, AttachmentID: attachmentID})
+}
+
+func newMediaTemplateMessage(r Recipient, e MediaElement) MediaTemplateMessage {
+	return MediaTemplateMessage{
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
+		Message: mediaTemplateContent{
+			Attachment: mediaTemplateAttachment{
+				Type: string(AttachmentTypeTemplate),
+				Payload: mediaTemplatePayload{
+					TemplateType: string(TemplateTypeMedia),
+					Elements:     []MediaElement{e},
+				},
+			},
+		},
+	}
+}
+
+// AddButton adds button b to Media Template message
+func (m *MediaTemplateMessage) AddButton(b Button) {
+	p := &m.Message.Attachment.Payload
+	if len(p.Elements) == 0 {
+		p.Elements = append(p.Elements, MediaElement{})
+	}
+	p.Elements[0].Buttons = append(p.Elements[0].Buttons, b)
+}
+
+// AddWebURLButton creates and adds web link URL button to Media Template message
+func (m *MediaTemplateMessage) AddWebURLButton(title, URL string) {
+	m.AddButton(Button{
+		Type:  ButtonTypeWebURL,
+		Title: title,
+		URL:   URL,
+	})
+}
+
+// AddPostbackButton creates and adds button that sends payload string back to webhook when pressed
+func (m *MediaTemplateMessage) AddPostbackButton(title, payload string) {
+	m.AddButton(Button{
+		Type:    ButtonTypePostback,
+		Title:   title,
+		Payload: payload,
+	})
+}
+
+// Validate checks that Media Template message contains single image or video
+// SendMessage calls Validate before sending the message
+func (m MediaTemplateMessage) Validate() error {
+	if n := len(m.Message.Attachment.Payload.Elements); n != 1 {
+		return fmt.Errorf("Media template has %d elements, it must have 1", n)
+	}
+	for _, e := range m.Message.Attachment.Payload.Elements {
+		if e.MediaType != AttachmentTypeImage && e.MediaType != AttachmentTypeVideo {
+			return fmt.Errorf("Media template media type is %q, it must be image or video", e.MediaType)
+		}
+	}
+	return nil
+}
+
+// NewButtonTemplateMessage creates new Button Template message with text for userID
+// Text can have up to 640 characters, add up to 3 buttons with AddWebURLButton, AddPostbackButton or AddButton
+func (msng Messenger) NewButtonTemplateMessage(userID int64, text string) ButtonTemplateMessage {
+	return msng.NewButtonTemplateMessageTo(Recipient{ID: userID}, text)
+}
+
+// NewButtonTemplateMessageTo creates new Button Template message with text for recipient r
+func (msng Messenger) NewButtonTemplateMessageTo(r Recipient, text string) ButtonTemplateMessage {
+	return ButtonTemplateMessage{
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
+		Message: buttonTemplateContent{
+			Attachment: buttonTemplateAttachment{
+				Type:    string(AttachmentTypeTemplate),
+				Payload: buttonTemplatePayload{TemplateType: string(TemplateTypeButton), Text: text},
+			},
+		},
+	}
+}
+
+// AddButton adds button b to Button Template message
+func (m *ButtonTemplateMessage) AddButton(b Button) {
+	m.Message.Attachment.Payload.Buttons = append(m.Message.Attachment.Payload.Buttons, b)
+}
+
+// AddWebURLButton creates and adds web link URL button to Button Template message
+func (m *ButtonTemplateMessage) AddWebURLButton(title, URL string) {
+	m.AddButton(Button{
+		Type:  ButtonTypeWebURL,
+		Title: title,
+		URL:   URL,
+	})
+}
+
+// AddPostbackButton creates and adds button that sends payload string back to webhook when pressed
+func (m *ButtonTemplateMessage) AddPostbackButton(title, payload string) {
+	m.AddButton(Button{
+		Type:    ButtonTypePostback,
+		Title:   title,
+		Payload: payload,
+	})
+}
+
+// Validate checks Button Template message limits, text up to 640 characters and 1 to 3 buttons
+// SendMessage calls Validate before sending the message
+func (m ButtonTemplateMessage) Validate() error {
+	p := m.Message.Attachment.Payload
+	if n := utf8.RuneCountInString(p.Text); n > maxButtonTemplateText {
+		return fmt.Errorf("Button template text has %d characters, maximum is %d", n, maxButtonTemplateText)
+	}
+	if len(p.Buttons) == 0 || len(p.Buttons) > maxButtonTemplateButtons {
+		return fmt.Errorf("Button template has %d buttons, it must have 1 to %d", len(p.Buttons), maxButtonTemplateButtons)
+	}
+	return nil
+}
+
+// NewImageMessage creates new message with image from URL for userID
+// If AttachmentCache is set, media messages reuse attachment ID of already sent or uploaded URL
+func (msng Messenger) NewImageMessage(userID int64, URL string) AttachmentMessage {
+	return msng.NewMediaMessageTo(Recipient{ID: userID}, AttachmentTypeImage, URL)
+}
+
+// NewAudioMessage creates new message with audio file from URL for userID
+func (msng Messenger) NewAudioMessage(userID int64, URL string) AttachmentMessage {
+	return msng.NewMediaMessageTo(Recipient{ID: userID}, AttachmentTypeAudio, URL)
+}
+
+// NewVideoMessage creates new message with video from URL for userID
+func (msng Messenger) NewVideoMessage(userID int64, URL string) AttachmentMessage {
+	return msng.NewMediaMessageTo(Recipient{ID: userID}, AttachmentTypeVideo, URL)
+}
+
+// NewFileMessage creates new message with file from URL for userID
+func (msng Messenger) NewFileMessage(userID int64, URL string) AttachmentMessage {
+	return msng.NewMediaMessageTo(Recipient{ID: userID}, AttachmentTypeFile, URL)
+}
+
+// NewAttachmentMessage creates new message for userID with previously uploaded attachment
+// attachmentID is returned from Facebook when you send reusable attachment or upload it
+func (msng Messenger) NewAttachmentMessage(userID int64, attType AttachmentType, attachmentID string) AttachmentMessage {
+	return msng.NewAttachmentMessageTo(Recipient{ID: userID}, attType, attachmentID)
+}
+
+// NewAttachmentMessageTo creates new message for recipient r with previously uploaded attachment
+func (msng Messenger) NewAttachmentMessageTo(r Recipient, attType AttachmentType, attachmentID string) AttachmentMessage {
+	return AttachmentMessage{
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
+		Message: genericMessageContent{
+			Attachment: &attachment{
+				Type:    string(attType),
+				Payload: payload{AttachmentID: attachmentID},
+			},
+		},
+	}
+}
+
+// NewMediaMessageTo creates new image, audio, video or file message from URL for recipient r
+// Attachment ID is used instead of URL if URL is found in AttachmentCache. If URL is not cached yet,
+// attachment is marked as reusable so its ID can be cached after sending
+func (msng Messenger) NewMediaMessageTo(r Recipient, attType AttachmentType, URL string) AttachmentMessage {
+	if msng.AttachmentCache != nil {
+		if id, ok := msng.AttachmentCache.Get(URL); ok {
+			return msng.NewAttachmentMessageTo(r, attType, id)
+		}
+	}
+
+	return AttachmentMessage{
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
+		Message: genericMessageContent{
+			Attachment: &attachment{
+				Type:    string(attType),
+				Payload: payload{URL: URL, IsReusable: msng.AttachmentCache != nil},
+			},
+		},
+	}
+}
+
+// SetReusable marks attachment sent by URL as reusable, so Facebook returns attachment ID
+// in FacebookResponse which can be used later with NewAttachmentMessage
+func (m *AttachmentMessage) SetReusable(reusable bool) {
+	m.Message.Attachment.Payload.IsReusable = reusable
+}
+
 // AddNewElement adds element to Generic template message with defined title, subtitle, link url and image url
 // Title param is mandatory. If not used set "" for other params and nil for buttons param
 // Generic messages can have up to 10 elements which are scolled horizontaly in Facebook messenger
@@ -187,3 +641,29 @@ func (e *Element) AddPostbackButton(title, payload string) {
 	}
 	e.Buttons = append(e.Buttons, b)
 }
+
+// AddQuickReply adds text quick reply to the message. When user taps it, payload is sent back
+// to webhook as FacebookMessage.QuickReply.Payload. Use "" for imageURL if you don't need an icon
+func (m *TextMessage) AddQuickReply(title, payload, imageURL string) {
+	m.Message.QuickReplies = append(m.Message.QuickReplies, QuickReply{
+		ContentType: QuickReplyContentTypeText,
+		Title:       title,
+		Payload:     payload,
+		ImageURL:    imageURL,
+	})
+}
+
+// AddLocationQuickReply adds quick reply that asks user to share his location
+func (m *TextMessage) AddLocationQuickReply() {
+	m.Message.QuickReplies = append(m.Message.QuickReplies, QuickReply{ContentType: QuickReplyContentTypeLocation})
+}
+
+// AddEmailQuickReply adds quick reply prefilled with user's email address from his Facebook profile
+func (m *TextMessage) AddEmailQuickReply() {
+	m.Message.QuickReplies = append(m.Message.QuickReplies, QuickReply{ContentType: QuickReplyContentTypeUserEmail})
+}
+
+// AddPhoneQuickReply adds quick reply prefilled with user's phone number from his Facebook profile
+func (m *TextMessage) AddPhoneQuickReply() {
+	m.Message.QuickReplies = append(m.Message.QuickReplies, QuickReply{ContentType: QuickReplyContentTypeUserPhoneNumber})
+}
@@ -2,13 +2,25 @@ package messenger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 const apiURL = "https://graph.facebook.com/v2.6/"
 
+// typingRefresh is interval for refreshing typing indicator, Facebook turns it off after 20 seconds
+const typingRefresh = 15 * time.Second
+
+// defaultHTTPClient is used for Graph API calls if Messenger HTTPClient is not set
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
 // TestURL to mock FB server, used for testing
 var TestURL = ""
 
@@ -18,8 +30,29 @@ type Messenger struct {
 	VerifyToken string
 	PageID      string
 
-	apiURL  string
-	pageURL string
+	// AppSecret is used for verifying X-Hub-Signature-256 header of webhook requests
+	// If set, requests with missing or invalid signature are rejected with 403 Forbidden
+	AppSecret string
+
+	// Retry policy for Graph API calls that failed with retryable error, like rate limiting or 5xx responses
+	// Omit (nil) if you don't want failed calls to be retried
+	Retry *RetryPolicy
+
+	// RateLimit limits Graph API calls per page and messages per recipient, use NewRateLimiter
+	// Omit (nil) if you don't want calls to be limited
+	RateLimit *RateLimiter
+
+	// HTTPClient is used for all Graph API calls, set it if you need custom timeouts, proxy or transport
+	// Omit (nil) to use default client with 30 seconds timeout
+	HTTPClient *http.Client
+
+	// AttachmentCache stores IDs of uploaded attachments so media messages can reuse them
+	// Omit (nil) if you don't want attachments to be reused
+	AttachmentCache AttachmentCache
+
+	// UserProfileCache stores user profiles returned by GetUserProfile
+	// New sets in-memory LRU cache, omit (nil) if you don't want profiles to be cached
+	UserProfileCache UserProfileCache
 
 	// MessageReceived event fires when message from Facebook received
 	MessageReceived func(msng *Messenger, userID int64, m FacebookMessage)
@@ -31,56 +64,177 @@ type Messenger struct {
 	// PostbackReceived event fires when postback received from Facebook server
 	// Omit (nil) if you don't use postbacks and you don't want to manage this events
 	PostbackReceived func(msng *Messenger, userID int64, p FacebookPostback)
+
+	// ReadReceived event fires when user reads messages sent by your page
+	// Omit (nil) if you don't want to manage this events
+	ReadReceived func(msng *Messenger, userID int64, r FacebookRead)
+
+	// EchoReceived event fires for messages sent by your page, userID is the user message was sent to
+	// Echoes are never sent to MessageReceived. Omit (nil) if you don't want to manage this events
+	EchoReceived func(msng *Messenger, userID int64, m FacebookMessage)
+
+	// ReactionReceived event fires when user reacts to message or removes reaction
+	// Omit (nil) if you don't want to manage this events
+	ReactionReceived func(msng *Messenger, userID int64, r FacebookReaction)
+
+	// ReferralReceived event fires when user already in conversation with your page arrives from m.me link, ad or Chat plugin
+	// Referrals of new users are received with Get Started postback. Omit (nil) if you don't want to manage this events
+	ReferralReceived func(msng *Messenger, userID int64, r FacebookReferral)
+
+	// OptinReceived event fires when user opts in with Send to Messenger or Checkbox plugin
+	// userID is 0 for Checkbox plugin, use UserRef instead. Omit (nil) if you don't use plugins
+	OptinReceived func(msng *Messenger, userID int64, o FacebookOptin)
+
+	// CommentReceived event fires when comment on your page's post is added, edited or removed,
+	// check Verb of the change. Requires feed webhook field. Omit (nil) if you don't want to manage this events
+	CommentReceived func(msng *Messenger, c FacebookFeedChange)
+
+	// PassThreadControlReceived event fires when another app passes thread control to your app
+	// Omit (nil) if you don't use Handover Protocol
+	PassThreadControlReceived func(msng *Messenger, userID int64, p FacebookPassThreadControl)
+
+	// TakeThreadControlReceived event fires when primary receiver takes thread control from your app
+	// Omit (nil) if you don't use Handover Protocol
+	TakeThreadControlReceived func(msng *Messenger, userID int64, t FacebookTakeThreadControl)
+
+	// RequestThreadControlReceived event fires when secondary receiver requests thread control from your app
+	// Omit (nil) if you don't use Handover Protocol
+	RequestThreadControlReceived func(msng *Messenger, userID int64, r FacebookRequestThreadControl)
+
+	// StandbyReceived event fires for events in conversations where your app is not the thread owner,
+	// like messages and postbacks in conversations handled by human agents in Page Inbox
+	// Omit (nil) if you don't want to observe conversations your app doesn't own
+	StandbyReceived func(msng *Messenger, userID int64, e FacebookMessaging)
+
+	// AppRolesReceived event fires when page admin changes app roles for Handover Protocol
+	// Omit (nil) if you don't use Handover Protocol
+	AppRolesReceived func(msng *Messenger, roles FacebookAppRoles)
 }
 
-// New creates new messenger instance
+// New creates new messenger instance with in-memory user profile cache
 func New(accessToken, pageID string) Messenger {
 	return Messenger{
-		AccessToken: accessToken,
-		PageID:      pageID,
+		AccessToken:      accessToken,
+		PageID:           pageID,
+		UserProfileCache: NewLRUUserProfileCache(DefaultUserProfileCacheSize, DefaultUserProfileCacheTTL),
 	}
 }
 
 // SendMessage sends chat message
+// Messages created with New... functions are sent with MessagingTypeResponse, if you want to send message
+// outside of 24 hours messaging window set MessagingType to MessagingTypeMessageTag and Tag before sending
 func (msng *Messenger) SendMessage(m Message) (FacebookResponse, error) {
-	if msng.apiURL == "" {
-		if TestURL != "" {
-			msng.apiURL = TestURL + "me/messages?access_token=" + msng.AccessToken // testing, mock FB URL
-		} else {
-			msng.apiURL = apiURL + "me/messages?access_token=" + msng.AccessToken
-		}
-	}
-
-	s, _ := json.Marshal(m)
-	log.Println("MESSAGE:", string(s))
-	req, err := http.NewRequest("POST", msng.apiURL, bytes.NewBuffer(s))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return FacebookResponse{}, err
-	}
+	return msng.SendMessageContext(context.Background(), m)
+}
 
-	return decodeResponse(resp)
+// SendMessageContext sends chat message, request is canceled when ctx is done
+func (msng *Messenger) SendMessageContext(ctx context.Context, m Message) (FacebookResponse, error) {
+	return msng.send(ctx, m, nil)
 }
 
 // SendTextMessage sends text messate to receiverID
 // it is shorthand instead of crating new text message and then sending it
 func (msng Messenger) SendTextMessage(receiverID int64, text string) (FacebookResponse, error) {
+	return msng.SendTextMessageContext(context.Background(), receiverID, text)
+}
+
+// SendTextMessageContext sends text message to receiverID, request is canceled when ctx is done
+func (msng Messenger) SendTextMessageContext(ctx context.Context, receiverID int64, text string) (FacebookResponse, error) {
 	m := msng.NewTextMessage(receiverID, text)
-	return msng.SendMessage(&m)
+	return msng.SendMessageContext(ctx, &m)
+}
+
+// SendTaggedTextMessage sends text message with message tag to receiverID
+// Use it for sending updates outside of 24 hours messaging window, like MessageTagAccountUpdate
+func (msng Messenger) SendTaggedTextMessage(receiverID int64, text string, tag MessageTag) (FacebookResponse, error) {
+	return msng.SendTaggedTextMessageContext(context.Background(), receiverID, text, tag)
+}
+
+// SendTaggedTextMessageContext sends text message with message tag to receiverID, request is canceled when ctx is done
+func (msng Messenger) SendTaggedTextMessageContext(ctx context.Context, receiverID int64, text string, tag MessageTag) (FacebookResponse, error) {
+	m := msng.NewTextMessage(receiverID, text)
+	m.MessagingType = MessagingTypeMessageTag
+	m.Tag = tag
+	return msng.SendMessageContext(ctx, &m)
+}
+
+// SendPrivateReply sends message m as private reply to comment on your page's post, recipient of m is replaced
+// with commentID. Only one private reply can be sent for each comment within 7 days after comment was made
+func (msng *Messenger) SendPrivateReply(ctx context.Context, commentID string, m Message) (FacebookResponse, error) {
+	return msng.send(ctx, m, &Recipient{CommentID: commentID})
+}
+
+// SendAction sends sender action to userID, i.e. turns typing indicator on or off or marks messages as seen
+func (msng Messenger) SendAction(userID int64, action SenderAction) error {
+	return msng.SendActionContext(context.Background(), userID, action)
+}
+
+// SendActionContext sends sender action to userID, request is canceled when ctx is done
+func (msng Messenger) SendActionContext(ctx context.Context, userID int64, action SenderAction) error {
+	m := msng.NewSenderActionMessage(userID, action)
+	_, err := msng.SendMessageContext(ctx, &m)
+	return err
+}
+
+// StartTyping turns typing indicator on for userID and keeps it alive until returned stop function is called
+// Use it while your handler does something slow, call stop when the reply is sent:
+//
+//	stop := msng.StartTyping(userID)
+//	defer stop()
+//
+// Stop function turns typing indicator off, it is safe to call it more than once
+func (msng *Messenger) StartTyping(userID int64) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(typingRefresh)
+		defer ticker.Stop()
+		for {
+			msng.SendActionContext(ctx, userID, SenderActionTypingOn)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel() // don't wait for typing_on that is still being sent
+			<-stopped
+			msng.SendAction(userID, SenderActionTypingOff)
+		})
+	}
 }
 
 // ServeHTTP is HTTP handler for Messenger so it could be directly used as http.Handler
 func (msng *Messenger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	msng.VerifyWebhook(w, r)    // verify webhook if needed
+	if r.Method == http.MethodGet {
+		msng.VerifyWebhook(w, r) // verify webhook if needed
+		return
+	}
+
+	if msng.AppSecret != "" {
+		if _, err := VerifySignature(r, msng.AppSecret); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
 	fbRq, _ := DecodeRequest(r) // get FacebookRequest object
 
 	for _, entry := range fbRq.Entry {
 		for _, msg := range entry.Messaging {
 			userID := msg.Sender.ID
 			switch {
+			case msg.Message != nil && msg.Message.IsEcho:
+				if msng.EchoReceived != nil {
+					go msng.EchoReceived(msng, msg.Recipient.ID, *msg.Message)
+				}
+
 			case msg.Message != nil && msng.MessageReceived != nil:
 				go msng.MessageReceived(msng, userID, *msg.Message)
 
@@ -89,6 +243,44 @@ func (msng *Messenger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 			case msg.Postback != nil && msng.PostbackReceived != nil:
 				go msng.PostbackReceived(msng, userID, *msg.Postback)
+
+			case msg.Read != nil && msng.ReadReceived != nil:
+				go msng.ReadReceived(msng, userID, *msg.Read)
+
+			case msg.Reaction != nil && msng.ReactionReceived != nil:
+				go msng.ReactionReceived(msng, userID, *msg.Reaction)
+
+			case msg.Referral != nil && msng.ReferralReceived != nil:
+				go msng.ReferralReceived(msng, userID, *msg.Referral)
+
+			case msg.Optin != nil && msng.OptinReceived != nil:
+				go msng.OptinReceived(msng, userID, *msg.Optin)
+
+			case msg.PassThreadControl != nil && msng.PassThreadControlReceived != nil:
+				go msng.PassThreadControlReceived(msng, userID, *msg.PassThreadControl)
+
+			case msg.TakeThreadControl != nil && msng.TakeThreadControlReceived != nil:
+				go msng.TakeThreadControlReceived(msng, userID, *msg.TakeThreadControl)
+
+			case msg.RequestThreadControl != nil && msng.RequestThreadControlReceived != nil:
+				go msng.RequestThreadControlReceived(msng, userID, *msg.RequestThreadControl)
+
+			case msg.AppRoles != nil && msng.AppRolesReceived != nil:
+				go msng.AppRolesReceived(msng, msg.AppRoles)
+			}
+		}
+
+		if msng.CommentReceived != nil {
+			for _, change := range entry.Changes {
+				if change.Field == "feed" && change.Value.Item == "comment" {
+					go msng.CommentReceived(msng, change.Value)
+				}
+			}
+		}
+
+		if msng.StandbyReceived != nil {
+			for _, msg := range entry.Standby {
+				go msng.StandbyReceived(msng, msg.Sender.ID, msg)
 			}
 		}
 	}
@@ -116,21 +308,178 @@ func DecodeRequest(r *http.Request) (FacebookRequest, error) {
 	return fbRq, err
 }
 
-// decodeResponse decodes Facebook response after sending message, usually contains MessageID or Error
-func decodeResponse(r *http.Response) (FacebookResponse, error) {
-	defer r.Body.Close()
-	var fbResp rawFBResponse
-	err := json.NewDecoder(r.Body).Decode(&fbResp)
+// send validates and sends message m, if r is not nil it replaces recipient of the message
+func (msng *Messenger) send(ctx context.Context, m Message, r *Recipient) (FacebookResponse, error) {
+	if v, ok := m.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return FacebookResponse{}, err
+		}
+	}
+
+	s, err := json.Marshal(m)
 	if err != nil {
 		return FacebookResponse{}, err
 	}
+	if r != nil {
+		if s, err = replaceRecipient(s, *r); err != nil {
+			return FacebookResponse{}, err
+		}
+	}
+
+	resp, err := msng.sendRaw(ctx, s)
+	if err != nil {
+		return FacebookResponse{}, err
+	}
+
+	msng.cacheAttachment(m, resp)
+	return resp, nil
+}
+
+// sendRaw posts JSON encoded message to Send API
+func (msng *Messenger) sendRaw(ctx context.Context, s []byte) (FacebookResponse, error) {
+	log.Println("MESSAGE:", string(s))
+	return msng.post(msng.recipientContext(ctx, s), "me/messages", "application/json", s)
+}
+
+// recipientContext returns ctx with recipient of JSON encoded message s if RateLimit is set
+func (msng *Messenger) recipientContext(ctx context.Context, s []byte) context.Context {
+	if msng.RateLimit == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, recipientKey{}, messageRecipient(s))
+}
+
+// messageRecipient returns recipient key of JSON encoded message
+func messageRecipient(s []byte) string {
+	var rm struct {
+		Recipient Recipient `json:"recipient"`
+	}
+	json.Unmarshal(s, &rm)
+	return rm.Recipient.key()
+}
+
+// replaceRecipient replaces recipient in JSON encoded message
+func replaceRecipient(msg []byte, r Recipient) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return nil, err
+	}
+	fields["recipient"], _ = json.Marshal(r)
+	return json.Marshal(fields)
+}
+
+// httpClient returns HTTPClient or default client with timeout if HTTPClient is not set
+func (msng *Messenger) httpClient() *http.Client {
+	if msng.HTTPClient != nil {
+		return msng.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
+// graphURL returns Graph API URL for path with access token, or mock FB URL if TestURL is set
+func (msng *Messenger) graphURL(path string) string {
+	base := apiURL
+	if TestURL != "" {
+		base = TestURL // testing, mock FB URL
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return base + path + sep + "access_token=" + url.QueryEscape(msng.AccessToken)
+}
+
+// post sends body to Graph API path and decodes Facebook response
+func (msng *Messenger) post(ctx context.Context, path, contentType string, body []byte) (FacebookResponse, error) {
+	var fbResp FacebookResponse
+	if err := msng.call(ctx, "POST", path, contentType, body, &fbResp); err != nil {
+		return FacebookResponse{}, err
+	}
+	return fbResp, nil
+}
+
+// callJSON sends in encoded as JSON (or no body if in is nil) to Graph API path and decodes JSON response into out
+func (msng *Messenger) callJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		body, _ = json.Marshal(in)
+	}
+	return msng.call(ctx, method, path, "application/json", body, out)
+}
+
+// call sends request to Graph API path and decodes response into out, out can be nil if response is not needed
+// Request is sent again if Retry policy is set and Facebook returned retryable error
+func (msng *Messenger) call(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	for attempt := 1; ; attempt++ {
+		err := msng.do(ctx, method, path, contentType, body, out)
+		if err == nil {
+			return nil
+		}
 
+		delay, retry := msng.Retry.delay(attempt, err)
+		if !retry {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// do sends single request to Graph API path and decodes response into out
+func (msng *Messenger) do(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	recipient, _ := ctx.Value(recipientKey{}).(string)
+	if err := msng.RateLimit.Wait(ctx, recipient); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, msng.graphURL(path), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := msng.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+
+	msng.RateLimit.update(resp.Header)
+	return decodeResponse(resp, out)
+}
+
+// decodeResponse decodes Facebook response, returns FacebookError if Facebook returned one
+// or if response has error HTTP status without Graph API error in the body
+func decodeResponse(r *http.Response, out interface{}) error {
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	var fbResp struct {
+		Error *FacebookError `json:"error"`
+	}
+	jsonErr := json.Unmarshal(data, &fbResp)
+	if fbResp.Error == nil && r.StatusCode >= http.StatusBadRequest {
+		// error response without Facebook error, like HTML from proxy or empty JSON
+		fbResp.Error = &FacebookError{Message: r.Status}
+	}
 	if fbResp.Error != nil {
-		return FacebookResponse{}, fbResp.Error.Error()
+		fbResp.Error.StatusCode = r.StatusCode
+		fbResp.Error.RetryAfter = retryAfter(r.Header)
+		return fbResp.Error
+	}
+	if jsonErr != nil {
+		return jsonErr
 	}
 
-	return FacebookResponse{
-		MessageID:   fbResp.MessageID,
-		RecipientID: fbResp.RecipientID,
-	}, nil
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
 }
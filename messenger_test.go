@@ -1,11 +1,22 @@
 package messenger_test
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mileusna/facebook-messenger"
 )
@@ -14,6 +25,12 @@ var fs *httptest.Server
 
 var ts *httptest.Server
 
+var uploads int
+
+var lastMessage []byte
+
+var lastRequest *http.Request
+
 const (
 	verifyToken = "my_secret_token"
 )
@@ -21,6 +38,36 @@ const (
 func TestMain(m *testing.M) {
 	// fs will mock up fb messenger server
 	fs = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		switch r.URL.Path {
+		case "/me/custom_user_settings":
+			lastMessage, _ = ioutil.ReadAll(r.Body)
+			if r.Method == "GET" {
+				w.Write([]byte(`{"data":[{"user_level_persistent_menu":[{"locale":"default","composer_input_disabled":false,"call_to_actions":[{"type":"postback","title":"Help","payload":"HELP"}]}]}]}`))
+			} else {
+				w.Write([]byte(`{"result":"success"}`))
+			}
+			return
+		case "/me/thread_owner":
+			w.Write([]byte(`{"data":[{"thread_owner":{"app_id":"263902037430900"}}]}`))
+			return
+		case "/me/secondary_receivers":
+			w.Write([]byte(`{"data":[{"id":"12345678910","name":"David's Composer"}]}`))
+			return
+		case "/me/message_attachments":
+			uploads++
+			w.Write([]byte(`{"attachment_id":"1857777774821032"}`))
+			return
+		case "/me/messenger_profile":
+			if r.Method == "GET" {
+				w.Write([]byte(`{"data":[{"greeting":[{"locale":"default","text":"Hello {{user_first_name}}"}]}]}`))
+			} else {
+				lastMessage, _ = ioutil.ReadAll(r.Body)
+				w.Write([]byte(`{"result":"success"}`))
+			}
+			return
+		}
+		lastMessage, _ = ioutil.ReadAll(r.Body)
 		rec := messenger.FacebookResponse{
 			RecipientID: 12123213123,
 			MessageID:   "mid00000TEST00000TEST00000TEST",
@@ -52,10 +99,1045 @@ func TestMain(m *testing.M) {
 func TestVerify(t *testing.T) {
 	challenge := "1122334455"
 	verifyReq := ts.URL + "/?test=1&hub.mode=subscribe&hub.challenge=" + challenge + "&hub.verify_token=" + verifyToken
-	resp, _ := http.Get(verifyReq)
+	resp, err := http.Get(verifyReq)
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer resp.Body.Close()
 	s, _ := ioutil.ReadAll(resp.Body)
 	if string(s) != challenge {
 		t.Error("Challenge failed, expected", challenge, "returned", string(s))
 	}
 }
+
+func TestSignature(t *testing.T) {
+	body := `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},"message":{"mid":"mid.1","text":"hi"}}]}]}`
+	mac := hmac.New(sha256.New, []byte("app_secret"))
+	mac.Write([]byte(body))
+	validSig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	received := make(chan string, 1)
+	msng := &messenger.Messenger{
+		AppSecret: "app_secret",
+		MessageReceived: func(msng *messenger.Messenger, userID int64, m messenger.FacebookMessage) {
+			received <- m.Text
+		},
+	}
+
+	for _, sig := range []string{"", "sha256=0000", "sha1=" + strings.Repeat("0", 40)} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		if sig != "" {
+			req.Header.Set("X-Hub-Signature-256", sig)
+		}
+		rec := httptest.NewRecorder()
+		msng.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Error("Signature", sig, "expected 403, returned", rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", validSig)
+	rec := httptest.NewRecorder()
+	msng.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Error("Valid signature expected 200, returned", rec.Code)
+	}
+	if text := <-received; text != "hi" {
+		t.Error("Expected message hi, received", text)
+	}
+
+	// legacy SHA1 signature is used if X-Hub-Signature-256 is not present
+	mac = hmac.New(sha1.New, []byte("app_secret"))
+	mac.Write([]byte(body))
+	for sig, code := range map[string]int{
+		"sha1=" + hex.EncodeToString(mac.Sum(nil)): http.StatusOK,
+		"sha1=" + strings.Repeat("0", 40):          http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature", sig)
+		rec := httptest.NewRecorder()
+		msng.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Error("Legacy signature", sig, "expected", code, "returned", rec.Code)
+		}
+	}
+	if text := <-received; text != "hi" {
+		t.Error("Expected message hi for legacy signature, received", text)
+	}
+}
+
+func TestQuickReply(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	m := msng.NewTextMessage(123, "Pick a color")
+	m.AddQuickReply("Red", "COLOR_RED", "")
+	m.AddEmailQuickReply()
+	b, _ := json.Marshal(m)
+	expected := `"quick_replies":[{"content_type":"text","title":"Red","payload":"COLOR_RED"},{"content_type":"user_email"}]`
+	if !strings.Contains(string(b), expected) {
+		t.Error("Expected", expected, "in", string(b))
+	}
+
+	body := `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},"message":{"mid":"mid.1","text":"Red","quick_reply":{"payload":"COLOR_RED"}}}]}]}`
+	fbRq, err := messenger.DecodeRequest(httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qr := fbRq.Entry[0].Messaging[0].Message.QuickReply
+	if qr == nil || qr.Payload != "COLOR_RED" {
+		t.Error("Expected quick reply payload COLOR_RED, received", qr)
+	}
+}
+
+func TestUploadAttachment(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	msng.AttachmentCache = messenger.NewMemoryAttachmentCache()
+
+	uploads = 0
+	for i := 0; i < 2; i++ {
+		id, err := msng.UploadAttachment(context.Background(), messenger.AttachmentTypeFile, strings.NewReader("%PDF-1.4"), "price-list.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != "1857777774821032" {
+			t.Error("Expected attachment ID 1857777774821032, returned", id)
+		}
+	}
+	if uploads != 1 {
+		t.Error("Expected content to be uploaded once, uploaded", uploads, "times")
+	}
+
+	if _, err := msng.UploadAttachmentURL(context.Background(), messenger.AttachmentTypeImage, "http://mysite.com/some-photo.jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	m := msng.NewImageMessage(123, "http://mysite.com/some-photo.jpeg")
+	b, _ := json.Marshal(m)
+	if !strings.Contains(string(b), `"attachment_id":"1857777774821032"`) {
+		t.Error("Expected cached attachment ID in", string(b))
+	}
+}
+
+func TestReceivedAttachments(t *testing.T) {
+	body := `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},"message":{"mid":"mid.1","attachments":[
+		{"type":"image","payload":{"url":"https://scontent.xx.fbcdn.net/like.png","sticker_id":369239263222822}},
+		{"type":"location","payload":{"coordinates":{"lat":44.8125,"long":20.4612}}},
+		{"type":"fallback","payload":null,"title":"My site","url":"http://mysite.com"}]}}]}]}`
+	fbRq, err := messenger.DecodeRequest(httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atts := fbRq.Entry[0].Messaging[0].Message.Attachments
+	if len(atts) != 3 {
+		t.Fatal("Expected 3 attachments, received", len(atts))
+	}
+	if !atts[0].IsLike() {
+		t.Error("Expected like sticker, received", atts[0])
+	}
+	if c := atts[1].Payload.Coordinates; atts[1].Type != messenger.AttachmentTypeLocation || c == nil || c.Lat != 44.8125 || c.Long != 20.4612 {
+		t.Error("Expected location 44.8125,20.4612, received", atts[1])
+	}
+	if atts[2].Type != messenger.AttachmentTypeFallback || atts[2].URL != "http://mysite.com" {
+		t.Error("Expected fallback with URL http://mysite.com, received", atts[2])
+	}
+}
+
+func TestButtonTemplateValidate(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	m := msng.NewButtonTemplateMessage(123, strings.Repeat("ž", 640))
+	m.AddPostbackButton("Ok", "OK")
+	if err := m.Validate(); err != nil {
+		t.Error("Expected valid message, returned", err)
+	}
+	s, _ := json.Marshal(m.Message)
+	if !strings.Contains(string(s), `"payload":{"template_type":"button","text":"`) || strings.Contains(string(s), "is_reusable") {
+		t.Error("Unexpected button template payload", string(s))
+	}
+
+	m.AddWebURLButton("One", "http://mysite.com/1")
+	m.AddWebURLButton("Two", "http://mysite.com/2")
+	m.AddWebURLButton("Three", "http://mysite.com/3")
+	if _, err := msng.SendMessage(m); err == nil {
+		t.Error("Expected error for 4 buttons")
+	}
+
+	m = msng.NewButtonTemplateMessage(123, strings.Repeat("a", 641))
+	m.AddPostbackButton("Ok", "OK")
+	if err := m.Validate(); err == nil {
+		t.Error("Expected error for 641 characters text")
+	}
+}
+
+func TestMessagingType(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	m := msng.NewGenericMessage(123)
+	b, _ := json.Marshal(m)
+	if !strings.Contains(string(b), `"messaging_type":"RESPONSE"`) {
+		t.Error("Expected default messaging type RESPONSE in", string(b))
+	}
+
+	m.MessagingType = messenger.MessagingTypeMessageTag
+	m.Tag = messenger.MessageTagPostPurchaseUpdate
+	b, _ = json.Marshal(m)
+	if !strings.Contains(string(b), `"messaging_type":"MESSAGE_TAG","tag":"POST_PURCHASE_UPDATE"`) {
+		t.Error("Expected message tag POST_PURCHASE_UPDATE in", string(b))
+	}
+}
+
+func TestMessengerProfile(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	if err := msng.SetGreeting(context.Background(), "Hello {{user_first_name}}"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := msng.GetMessengerProfile(context.Background(), messenger.ProfileFieldGreeting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Greeting) != 1 || p.Greeting[0].Text != "Hello {{user_first_name}}" {
+		t.Error("Expected greeting Hello {{user_first_name}}, received", p.Greeting)
+	}
+
+	if err := msng.DeleteMessengerProfile(context.Background(), messenger.ProfileFieldGreeting); err != nil {
+		t.Error(err)
+	}
+
+	if err := msng.DeleteWelcome(); err != nil || string(lastMessage) != `{"fields":["greeting"]}` {
+		t.Error("Expected DeleteWelcome to delete only greeting, sent", string(lastMessage), "error", err)
+	}
+	if err := msng.SetWelcomeGeneric(msng.NewGenericMessage(0)); !errors.Is(err, messenger.ErrWelcomeMessageUnsupported) {
+		t.Error("Expected ErrWelcomeMessageUnsupported, returned", err)
+	}
+}
+
+func TestLRUUserProfileCache(t *testing.T) {
+	c := messenger.NewLRUUserProfileCache(2, time.Hour)
+	c.Set("1", messenger.UserProfile{ID: 1, FirstName: "Ana"})
+	c.Set("2", messenger.UserProfile{ID: 2, FirstName: "Marko"})
+	c.Get("1")
+	c.Set("3", messenger.UserProfile{ID: 3, FirstName: "Jovan"})
+
+	if _, ok := c.Get("2"); ok {
+		t.Error("Expected least recently used profile 2 to be removed")
+	}
+	if p, ok := c.Get("1"); !ok || p.FirstName != "Ana" {
+		t.Error("Expected profile 1 in cache, received", p)
+	}
+
+	c = messenger.NewLRUUserProfileCache(2, -time.Second)
+	c.Set("1", messenger.UserProfile{ID: 1})
+	if _, ok := c.Get("1"); ok {
+		t.Error("Expected expired profile not to be returned")
+	}
+}
+
+func TestGetUserProfile(t *testing.T) {
+	var requests []string
+	msng := messenger.New("XXXXXXX", "")
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		return http.StatusOK, `{"first_name":"Peter","last_name":"Chang","profile_pic":"https://example.com/pic.jpg","id":"123"}`, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		p, err := msng.GetUserProfile(context.Background(), 123)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != 123 || p.FirstName != "Peter" || p.LastName != "Chang" || p.ProfilePic != "https://example.com/pic.jpg" {
+			t.Error("Unexpected user profile", p)
+		}
+	}
+	if len(requests) != 1 {
+		t.Fatal("Expected second profile to be returned from cache, requests", requests)
+	}
+	if !strings.HasSuffix(requests[0], "/123?fields=first_name,last_name,profile_pic&access_token=XXXXXXX") {
+		t.Error("Unexpected user profile request", requests[0])
+	}
+
+	if _, err := msng.GetUserProfile(context.Background(), 123, messenger.UserProfileFieldLocale); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || !strings.Contains(requests[1], "fields=locale&") {
+		t.Error("Expected profile with other fields to be requested, requests", requests)
+	}
+}
+
+// postWebhook sends webhook request with body directly to msng handler
+func postWebhook(msng *messenger.Messenger, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	msng.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	return rec
+}
+
+func TestHandoverEvents(t *testing.T) {
+	passed := make(chan messenger.FacebookPassThreadControl, 1)
+	taken := make(chan messenger.FacebookTakeThreadControl, 1)
+	requested := make(chan messenger.FacebookRequestThreadControl, 1)
+	roles := make(chan messenger.FacebookAppRoles, 1)
+	msng := &messenger.Messenger{
+		PassThreadControlReceived: func(msng *messenger.Messenger, userID int64, p messenger.FacebookPassThreadControl) {
+			passed <- p
+		},
+		TakeThreadControlReceived: func(msng *messenger.Messenger, userID int64, tc messenger.FacebookTakeThreadControl) {
+			taken <- tc
+		},
+		RequestThreadControlReceived: func(msng *messenger.Messenger, userID int64, r messenger.FacebookRequestThreadControl) {
+			requested <- r
+		},
+		AppRolesReceived: func(msng *messenger.Messenger, r messenger.FacebookAppRoles) {
+			roles <- r
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},
+		"pass_thread_control":{"previous_owner_app_id":"987654321","new_owner_app_id":"123456789","metadata":"order 1234"}}]}]}`)
+	if p := <-passed; p.NewOwnerAppID != 123456789 || p.PreviousOwnerAppID != 987654321 || p.Metadata != "order 1234" {
+		t.Error("Expected thread control passed from 987654321 to 123456789 with metadata, received", p)
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},
+		"take_thread_control":{"previous_owner_app_id":"123456789","new_owner_app_id":"987654321","metadata":"taken"}}]}]}`)
+	if tc := <-taken; tc.PreviousOwnerAppID != 123456789 || tc.NewOwnerAppID != 987654321 || tc.Metadata != "taken" {
+		t.Error("Expected thread control taken from 123456789 by 987654321, received", tc)
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},
+		"request_thread_control":{"requested_owner_app_id":123456789,"metadata":"please"}}]}]}`)
+	if r := <-requested; r.RequestedOwnerAppID != 123456789 || r.Metadata != "please" {
+		t.Error("Expected thread control requested by 123456789, received", r)
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"recipient":{"id":"1"},"timestamp":1458692752478,
+		"app_roles":{"123456789":["primary_receiver"]}}]}]}`)
+	if r := <-roles; len(r["123456789"]) != 1 || r["123456789"][0] != "primary_receiver" {
+		t.Error("Expected app 123456789 to be primary receiver, received", r)
+	}
+}
+
+func TestStandby(t *testing.T) {
+	standby := make(chan messenger.FacebookMessaging, 1)
+	msng := &messenger.Messenger{
+		MessageReceived: func(msng *messenger.Messenger, userID int64, m messenger.FacebookMessage) {
+			t.Error("Standby message should not be received as message")
+		},
+		StandbyReceived: func(msng *messenger.Messenger, userID int64, e messenger.FacebookMessaging) {
+			standby <- e
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"standby":[{"sender":{"id":"123"},"recipient":{"id":"1"},"message":{"mid":"mid.1","text":"I need a human"}}]}]}`)
+	if e := <-standby; e.Sender.ID != 123 || e.Message == nil || e.Message.Text != "I need a human" {
+		t.Error("Expected standby message from 123, received", e)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	echoes := make(chan int64, 1)
+	msng := &messenger.Messenger{
+		MessageReceived: func(msng *messenger.Messenger, userID int64, m messenger.FacebookMessage) {
+			t.Error("Echo should not be received as message")
+		},
+		EchoReceived: func(msng *messenger.Messenger, userID int64, m messenger.FacebookMessage) {
+			echoes <- userID
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"1"},"recipient":{"id":"123"},
+		"message":{"is_echo":true,"app_id":1517776481860111,"metadata":"DEVELOPER_DEFINED_METADATA","mid":"mid.1","text":"Hello there"}}]}]}`)
+	if userID := <-echoes; userID != 123 {
+		t.Error("Expected echo of message sent to 123, received", userID)
+	}
+}
+
+func TestRead(t *testing.T) {
+	reads := make(chan messenger.FacebookRead, 1)
+	msng := &messenger.Messenger{
+		ReadReceived: func(msng *messenger.Messenger, userID int64, r messenger.FacebookRead) {
+			if userID != 123 {
+				t.Error("Expected read by 123, received", userID)
+			}
+			reads <- r
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},"timestamp":1458668856463,
+		"read":{"watermark":1458668856253,"seq":38}}]}]}`)
+	if r := <-reads; r.Watermark != 1458668856253 || r.Seq != 38 {
+		t.Error("Expected read watermark 1458668856253, received", r)
+	}
+}
+
+func TestMeLink(t *testing.T) {
+	type campaign struct {
+		Source string `json:"s"`
+		ID     int    `json:"id"`
+	}
+
+	ref, err := messenger.EncodeRef(campaign{Source: "newsletter", ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := messenger.MeLink("mypage", ref)
+	if !strings.HasPrefix(link, "https://m.me/mypage?ref=") {
+		t.Error("Unexpected m.me link", link)
+	}
+
+	var c campaign
+	if err := messenger.DecodeRef(ref, &c); err != nil || c.Source != "newsletter" || c.ID != 42 {
+		t.Error("Expected decoded ref newsletter 42, received", c, err)
+	}
+}
+
+func TestRecipient(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	m := msng.NewTextMessageTo(messenger.Recipient{UserRef: "UNIQUE_REF_PARAM"}, "Thanks for subscribing")
+	b, _ := json.Marshal(m)
+	if !strings.Contains(string(b), `"recipient":{"user_ref":"UNIQUE_REF_PARAM"}`) {
+		t.Error("Expected user_ref recipient in", string(b))
+	}
+
+	m = msng.NewTextMessage(123, "Hello")
+	b, _ = json.Marshal(m)
+	if !strings.Contains(string(b), `"recipient":{"id":"123"}`) {
+		t.Error("Expected id recipient in", string(b))
+	}
+}
+
+func TestPrivateReply(t *testing.T) {
+	comments := make(chan messenger.FacebookFeedChange, 1)
+	msng := &messenger.Messenger{
+		AccessToken: "XXXXXXX",
+		CommentReceived: func(msng *messenger.Messenger, c messenger.FacebookFeedChange) {
+			comments <- c
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"changes":[{"field":"feed","value":{"item":"comment","verb":"add",
+		"post_id":"1_2","comment_id":"2_3","message":"link please","created_time":1590000000,"from":{"id":"4","name":"Ana"}}}]}]}`)
+	c := <-comments
+	if c.CommentID != "2_3" || c.Message != "link please" {
+		t.Fatal("Expected comment 2_3, received", c)
+	}
+
+	m := msng.NewTextMessage(0, "Here is the link")
+	if _, err := msng.SendPrivateReply(context.Background(), c.CommentID, m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(lastMessage), `"recipient":{"comment_id":"2_3"}`) {
+		t.Error("Expected comment_id recipient in", string(lastMessage))
+	}
+}
+
+func TestReaction(t *testing.T) {
+	reactions := make(chan messenger.FacebookReaction, 1)
+	msng := &messenger.Messenger{
+		ReactionReceived: func(msng *messenger.Messenger, userID int64, r messenger.FacebookReaction) {
+			reactions <- r
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},
+		"reaction":{"reaction":"love","emoji":"❤️","action":"react","mid":"mid.1"}}]}]}`)
+	if r := <-reactions; r.Action != "react" || r.Reaction != "love" || r.Mid != "mid.1" {
+		t.Error("Expected love reaction to mid.1, received", r)
+	}
+}
+
+func TestReferralAndOptin(t *testing.T) {
+	referrals := make(chan messenger.FacebookReferral, 1)
+	postbacks := make(chan messenger.FacebookPostback, 1)
+	optins := make(chan int64, 1)
+	var optin messenger.FacebookOptin
+	msng := &messenger.Messenger{
+		ReferralReceived: func(msng *messenger.Messenger, userID int64, r messenger.FacebookReferral) {
+			referrals <- r
+		},
+		PostbackReceived: func(msng *messenger.Messenger, userID int64, p messenger.FacebookPostback) {
+			postbacks <- p
+		},
+		OptinReceived: func(msng *messenger.Messenger, userID int64, o messenger.FacebookOptin) {
+			optin = o
+			optins <- userID
+		},
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},
+		"referral":{"ref":"summer","source":"SHORTLINK","type":"OPEN_THREAD"}}]}]}`)
+	if r := <-referrals; r.Ref != "summer" || r.Source != "SHORTLINK" {
+		t.Error("Expected shortlink referral summer, received", r)
+	}
+
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"sender":{"id":"123"},"recipient":{"id":"1"},
+		"postback":{"title":"Get Started","payload":"GET_STARTED","referral":{"ref":"ad1","source":"ADS","type":"OPEN_THREAD","ad_id":"6045246247433"}}}]}]}`)
+	if p := <-postbacks; p.Payload != "GET_STARTED" || p.Referral == nil || p.Referral.AdID != "6045246247433" {
+		t.Error("Expected Get Started postback with ad referral, received", p)
+	}
+
+	// Checkbox plugin opt-in has no sender, only user_ref
+	postWebhook(msng, `{"object":"page","entry":[{"id":1,"messaging":[{"recipient":{"id":"1"},"timestamp":1234567890,
+		"optin":{"ref":"checkout","user_ref":"UNIQUE_REF_PARAM"}}]}]}`)
+	if userID := <-optins; userID != 0 || optin.UserRef != "UNIQUE_REF_PARAM" || optin.Ref != "checkout" {
+		t.Error("Expected Checkbox opt-in without sender, received", userID, optin)
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestHTTPClient(t *testing.T) {
+	calls := 0
+	msng := messenger.New("XXXXXXX", "")
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		calls++
+		return http.StatusOK, `{"recipient_id":"123","message_id":"mid.1"}`, nil
+	})
+
+	resp, err := msng.SendTextMessage(123, "Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || resp.MessageID != "mid.1" {
+		t.Error("Expected message sent with custom HTTP client, calls", calls, "response", resp)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := msng.SendTextMessageContext(ctx, 123, "Hello"); err == nil {
+		t.Error("Expected error for canceled context")
+	}
+}
+
+// respondWith returns HTTP client that responds to every request with status and body
+func respondWith(status int, body string) *http.Client {
+	return respondFunc(func(r *http.Request) (int, string, http.Header) {
+		return status, body, nil
+	})
+}
+
+// respondFunc returns HTTP client that responds to request with status, body and headers returned by f
+// Like real transport, it returns error if request context is done
+func respondFunc(f func(r *http.Request) (status int, body string, h http.Header)) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		status, body, h := f(r)
+		if err := r.Context().Err(); err != nil {
+			return nil, err
+		}
+		if h == nil {
+			h = make(http.Header)
+		}
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Header:     h,
+		}, nil
+	})}
+}
+
+func TestFacebookError(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	msng.HTTPClient = respondWith(http.StatusBadRequest, `{"error":{"message":"This person isn't available right now.","type":"OAuthException","code":551,"error_subcode":1545041,"fbtrace_id":"BLBz/WZt8dN"}}`)
+
+	_, err := msng.SendTextMessage(123, "Hello")
+	if !errors.Is(err, messenger.ErrUserUnavailable) {
+		t.Error("Expected ErrUserUnavailable, returned", err)
+	}
+	var fbErr *messenger.FacebookError
+	if !errors.As(err, &fbErr) || fbErr.StatusCode != http.StatusBadRequest || fbErr.IsRetryable() {
+		t.Error("Expected non retryable FacebookError with status 400, returned", err)
+	}
+
+	msng.HTTPClient = respondWith(http.StatusBadRequest, `{"error":{"message":"Calls to this api have exceeded the rate limit.","type":"OAuthException","code":613}}`)
+	_, err = msng.SendTextMessage(123, "Hello")
+	if !errors.Is(err, messenger.ErrRateLimited) || !errors.As(err, &fbErr) || !fbErr.IsRetryable() {
+		t.Error("Expected retryable ErrRateLimited, returned", err)
+	}
+
+	msng.HTTPClient = respondWith(http.StatusBadGateway, `<html>Bad Gateway</html>`)
+	_, err = msng.SendTextMessage(123, "Hello")
+	if !errors.As(err, &fbErr) || !fbErr.IsRetryable() {
+		t.Error("Expected retryable error for 502 response, returned", err)
+	}
+
+	msng.HTTPClient = respondWith(http.StatusForbidden, `{}`)
+	_, err = msng.SendTextMessage(123, "Hello")
+	if !errors.As(err, &fbErr) || fbErr.StatusCode != http.StatusForbidden {
+		t.Error("Expected FacebookError for 403 response without error, returned", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	msng := messenger.New("XXXXXXX", "")
+	msng.Retry = &messenger.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		calls++
+		if calls < 3 {
+			return http.StatusInternalServerError, `{"error":{"message":"An unknown error occurred","type":"OAuthException","code":1}}`, nil
+		}
+		return http.StatusOK, `{"recipient_id":"123","message_id":"mid.1"}`, nil
+	})
+
+	if _, err := msng.SendTextMessage(123, "Hello"); err != nil || calls != 3 {
+		t.Error("Expected message sent on third attempt, attempts", calls, "error", err)
+	}
+
+	calls = 0
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		calls++
+		return http.StatusBadRequest, `{"error":{"message":"This person isn't available right now.","type":"OAuthException","code":551}}`, nil
+	})
+	if _, err := msng.SendTextMessage(123, "Hello"); err == nil || calls != 1 {
+		t.Error("Expected non retryable error to be returned after first attempt, attempts", calls)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := messenger.NewRateLimiter(100, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, "123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, "456"); err != nil {
+		t.Error("Expected message to other recipient to be allowed, returned", err)
+	}
+	if err := l.Wait(ctx, "123"); err == nil {
+		t.Error("Expected second message to the same recipient to wait longer than context timeout")
+	}
+
+	msng := messenger.New("XXXXXXX", "")
+	msng.RateLimit = messenger.NewRateLimiter(100, 0)
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		h := make(http.Header)
+		h.Set("X-Page-Usage", `{"call_count":100,"total_cputime":20,"total_time":30}`)
+		return http.StatusOK, `{"recipient_id":"123","message_id":"mid.1"}`, h
+	})
+	if _, err := msng.SendTextMessage(123, "Hello"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := msng.SendTextMessageContext(ctx, 456, "Hello"); err == nil {
+		t.Error("Expected calls to be paused after page usage reached 100%")
+	}
+
+	// canceled waits give tokens back
+	l = messenger.NewRateLimiter(0, 20)
+	for i := 0; i < 20; i++ {
+		l.Wait(context.Background(), "123")
+	}
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		l.Wait(ctx, "123")
+		cancel()
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "123"); err != nil {
+		t.Error("Expected canceled waits not to delay next message, returned", err)
+	}
+
+	// response without usage headers doesn't undo slowdown
+	l = messenger.NewRateLimiter(0, 10)
+	msng.RateLimit = l
+	pageUsage := `{"call_count":99,"total_cputime":20,"total_time":30}`
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		h := make(http.Header)
+		if pageUsage != "" {
+			h.Set("X-Page-Usage", pageUsage)
+			pageUsage = ""
+		}
+		return http.StatusOK, `{"recipient_id":"123","message_id":"mid.1"}`, h
+	})
+	for _, userID := range []int64{1, 2} {
+		if _, err := msng.SendTextMessage(userID, "Hello"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	l.Wait(ctx, "3")
+	if err := l.Wait(ctx, "3"); err == nil {
+		t.Error("Expected rate to stay slowed down after response without usage headers")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := messenger.NewFileQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []string
+	msng := messenger.New("XXXXXXX", "")
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		var m messenger.TextMessage
+		json.NewDecoder(r.Body).Decode(&m)
+		first := len(sent) == 0 && m.Message.Text == "First"
+		sent = append(sent, m.Message.Text)
+		if first {
+			return http.StatusInternalServerError, `{"error":{"message":"An unknown error occurred","type":"OAuthException","code":1}}`, nil
+		}
+		return http.StatusOK, `{"recipient_id":"123","message_id":"mid.1"}`, nil
+	})
+
+	q := messenger.NewQueue(&msng, store)
+	for _, text := range []string{"First", "Second"} {
+		if _, err := q.Enqueue(msng.NewTextMessage(123, text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if items, _ := store.Load(); len(items) != 2 {
+		t.Fatal("Expected 2 messages stored before queue is started, stored", len(items))
+	}
+
+	// file left by crash while saving doesn't block the queue
+	if err := ioutil.WriteFile(filepath.Join(dir, "truncated.json"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// queue created after restart sends messages left in store before newly enqueued ones
+	q = messenger.NewQueue(&msng, store)
+	q.Retry = messenger.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	done := make(chan error)
+	q.Completed = func(item messenger.QueueItem, resp messenger.FacebookResponse, err error) {
+		done <- err
+	}
+	if _, err := q.Enqueue(msng.NewTextMessage(123, "Third")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Error("Expected queued message to be sent, returned", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Queued messages were not sent")
+		}
+	}
+	if strings.Join(sent, ",") != "First,First,Second,Third" {
+		t.Error("Expected messages sent in order and failed message retried before next one, sent", sent)
+	}
+	if items, _ := store.Load(); len(items) != 0 {
+		t.Error("Expected sent messages to be removed from store, stored", len(items))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "truncated.json.corrupt")); err != nil {
+		t.Error("Expected corrupt item to be set aside", err)
+	}
+
+	// queued message is sent once per queue attempt even if Messenger Retry is set
+	sent = nil
+	msng.Retry = &messenger.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	calls := 0
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		calls++
+		return http.StatusInternalServerError, `{"error":{"message":"An unknown error occurred","type":"OAuthException","code":1}}`, nil
+	})
+	if _, err := q.Enqueue(msng.NewTextMessage(123, "Fail")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil || calls != 2 {
+			t.Error("Expected message to fail after 2 attempts, attempts", calls, "error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Failed message was not reported")
+	}
+}
+
+func TestQueueRestart(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	msng.HTTPClient = respondWith(http.StatusOK, `{"recipient_id":"123","message_id":"mid.1"}`)
+
+	var mu sync.Mutex
+	sent := make(map[string]bool)
+	q := messenger.NewQueue(&msng, messenger.NewMemoryQueueStore())
+	q.Completed = func(item messenger.QueueItem, resp messenger.FacebookResponse, err error) {
+		mu.Lock()
+		sent[item.ID] = err == nil
+		mu.Unlock()
+	}
+
+	// messages enqueued while queue is stopping are sent after it is started again
+	var ids []string
+	for i := 0; i < 5; i++ {
+		if err := q.Start(); err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for j := 0; j < 20; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id, err := q.Enqueue(msng.NewTextMessage(123, "Hello"))
+				if err != nil {
+					t.Error(err)
+				}
+				mu.Lock()
+				ids = append(ids, id)
+				mu.Unlock()
+			}()
+		}
+		q.Stop()
+		wg.Wait()
+	}
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		mu.Lock()
+		n := len(sent)
+		mu.Unlock()
+		if n == len(ids) {
+			break
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range ids {
+		if !sent[id] {
+			t.Error("Expected message", id, "to be sent")
+		}
+	}
+}
+
+func TestStartTyping(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	msng := messenger.New("XXXXXXX", "")
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		var m messenger.SenderActionMessage
+		json.NewDecoder(r.Body).Decode(&m)
+		mu.Lock()
+		actions = append(actions, string(m.SenderAction))
+		mu.Unlock()
+		return http.StatusOK, `{"recipient_id":"123"}`, nil
+	})
+
+	stop := msng.StartTyping(123)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(actions) != 2 || actions[0] != "typing_on" || actions[1] != "typing_off" {
+		t.Error("Expected typing_on and typing_off after stop, sent", actions)
+	}
+
+	// stop cancels typing_on which is still being sent
+	msng.HTTPClient = respondFunc(func(r *http.Request) (int, string, http.Header) {
+		var m messenger.SenderActionMessage
+		json.NewDecoder(r.Body).Decode(&m)
+		if m.SenderAction == messenger.SenderActionTypingOn {
+			<-r.Context().Done()
+		}
+		return http.StatusOK, `{"recipient_id":"123"}`, nil
+	})
+	stop = msng.StartTyping(123)
+	time.Sleep(10 * time.Millisecond)
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("Expected stop not to wait for typing_on to be sent")
+	}
+}
+
+func TestReceiptMessage(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	m := msng.NewReceiptMessage(123, "Stephane Crozatier", "12345678902", "USD", "Visa 2345")
+	m.AddNewElement("Classic White T-Shirt", "100% Soft and Luxurious Cotton", 2, 50, "")
+	m.AddElement(messenger.ReceiptElement{Title: "Classic Gray T-Shirt", Price: 25, Currency: "USD"})
+	m.SetAddress(messenger.ReceiptAddress{Street1: "1 Hacker Way", City: "Menlo Park", PostalCode: "94025", State: "CA", Country: "US"})
+	m.AddAdjustment("New Customer Discount", 20)
+	m.SetSummary(messenger.ReceiptSummary{Subtotal: 75, TotalCost: 56.14})
+	m.SetTimestamp(time.Unix(1428444852, 0))
+
+	s, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r struct {
+		Message struct {
+			Attachment struct {
+				Type    string                 `json:"type"`
+				Payload map[string]interface{} `json:"payload"`
+			} `json:"attachment"`
+		} `json:"message"`
+	}
+	json.Unmarshal(s, &r)
+	p := r.Message.Attachment.Payload
+
+	if r.Message.Attachment.Type != "template" || p["template_type"] != "receipt" {
+		t.Error("Expected receipt template, sent", string(s))
+	}
+	if p["timestamp"] != "1428444852" {
+		t.Errorf("Expected timestamp as JSON string, sent %#v", p["timestamp"])
+	}
+	if summary, _ := p["summary"].(map[string]interface{}); summary["total_cost"] != 56.14 {
+		t.Error("Expected summary total_cost, sent", p["summary"])
+	}
+	if address, _ := p["address"].(map[string]interface{}); address["street_1"] != "1 Hacker Way" {
+		t.Error("Expected address street_1, sent", p["address"])
+	}
+	if elements, _ := p["elements"].([]interface{}); len(elements) != 2 {
+		t.Error("Expected 2 elements, sent", p["elements"])
+	}
+	if adjustments, _ := p["adjustments"].([]interface{}); len(adjustments) != 1 {
+		t.Error("Expected 1 adjustment, sent", p["adjustments"])
+	}
+
+	// total_cost is required even if it is zero
+	m = msng.NewReceiptMessage(123, "Stephane Crozatier", "12345678902", "USD", "Visa 2345")
+	if s, _ := json.Marshal(m); !strings.Contains(string(s), `"total_cost":0`) {
+		t.Error("Expected total_cost in empty summary, sent", string(s))
+	}
+}
+
+func TestMediaTemplateMessage(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	m := msng.NewMediaTemplateMessage(123, messenger.AttachmentTypeImage, "https://www.facebook.com/photo.php?fbid=1")
+	m.AddWebURLButton("Open", "http://mysite.com")
+	if err := m.Validate(); err != nil {
+		t.Error("Expected valid message, returned", err)
+	}
+	s, _ := json.Marshal(m.Message)
+	expected := `{"attachment":{"type":"template","payload":{"template_type":"media","elements":[{"media_type":"image","url":"https://www.facebook.com/photo.php?fbid=1","buttons":[{"type":"web_url","url":"http://mysite.com","title":"Open"}]}]}}}`
+	if string(s) != expected {
+		t.Error("Unexpected media template", string(s))
+	}
+
+	m = msng.NewMediaTemplateMessageByID(123, messenger.AttachmentTypeVideo, "1854626884821032")
+	s, _ = json.Marshal(m.Message)
+	if !strings.Contains(string(s), `"elements":[{"media_type":"video","attachment_id":"1854626884821032"}]`) {
+		t.Error("Expected element with attachment ID, sent", string(s))
+	}
+
+	m = msng.NewMediaTemplateMessageByID(123, messenger.AttachmentTypeFile, "1854626884821032")
+	if _, err := msng.SendMessage(m); err == nil {
+		t.Error("Expected error for file media type")
+	}
+
+	var empty messenger.MediaTemplateMessage
+	empty.AddPostbackButton("Ok", "OK")
+	if err := empty.Validate(); err == nil {
+		t.Error("Expected error for media template without media")
+	}
+}
+
+func TestPersistentMenu(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	menu := msng.NewPersistentMenu(messenger.DefaultLocale, false)
+	menu.AddPostbackItem("Help", "HELP")
+	shop := messenger.MenuItem{Type: messenger.ButtonTypeNested, Title: "Shop"}
+	shop.AddWebURLItem("Catalog", "http://mysite.com/catalog")
+	menu.AddNestedItem(shop.Title, shop.CallToActions)
+
+	if err := msng.SetPersistentMenu(context.Background(), menu); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"persistent_menu":[{"locale":"default","composer_input_disabled":false,"call_to_actions":[` +
+		`{"type":"postback","title":"Help","payload":"HELP"},` +
+		`{"type":"nested","title":"Shop","call_to_actions":[{"type":"web_url","title":"Catalog","url":"http://mysite.com/catalog"}]}]}]}`
+	if string(lastMessage) != expected {
+		t.Error("Unexpected persistent menu", string(lastMessage))
+	}
+
+	if err := msng.SetUserPersistentMenu(context.Background(), 123, menu); err != nil {
+		t.Fatal(err)
+	}
+	if lastRequest.Method != "POST" || !strings.HasPrefix(string(lastMessage), `{"psid":"123","persistent_menu":[{"locale":"default"`) {
+		t.Error("Unexpected user persistent menu", lastRequest.Method, string(lastMessage))
+	}
+
+	menus, err := msng.GetUserPersistentMenu(context.Background(), 123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastRequest.URL.Query().Get("psid") != "123" {
+		t.Error("Expected psid 123, requested", lastRequest.URL.RawQuery)
+	}
+	if len(menus) != 1 || len(menus[0].CallToActions) != 1 || menus[0].CallToActions[0].Payload != "HELP" {
+		t.Error("Expected user menu with Help item, received", menus)
+	}
+
+	if err := msng.DeleteUserPersistentMenu(context.Background(), 123); err != nil {
+		t.Fatal(err)
+	}
+	q := lastRequest.URL.Query()
+	if lastRequest.Method != "DELETE" || q.Get("psid") != "123" || q.Get("params") != `["persistent_menu"]` {
+		t.Error("Unexpected user persistent menu delete", lastRequest.Method, lastRequest.URL.RawQuery)
+	}
+}
+
+func TestHandover(t *testing.T) {
+	msng := messenger.New("XXXXXXX", "")
+	if err := msng.PassThreadControl(context.Background(), 123, messenger.PageInboxAppID, "agent needed"); err != nil {
+		t.Fatal(err)
+	}
+	if lastRequest.URL.Path != "/me/pass_thread_control" || string(lastMessage) != `{"recipient":{"id":"123"},"target_app_id":263902037430900,"metadata":"agent needed"}` {
+		t.Error("Unexpected pass thread control", lastRequest.URL.Path, string(lastMessage))
+	}
+
+	if err := msng.TakeThreadControl(context.Background(), 123, ""); err != nil {
+		t.Fatal(err)
+	}
+	if lastRequest.URL.Path != "/me/take_thread_control" || string(lastMessage) != `{"recipient":{"id":"123"}}` {
+		t.Error("Unexpected take thread control", lastRequest.URL.Path, string(lastMessage))
+	}
+
+	if err := msng.RequestThreadControl(context.Background(), 123, "please"); err != nil {
+		t.Fatal(err)
+	}
+	if lastRequest.URL.Path != "/me/request_thread_control" || string(lastMessage) != `{"recipient":{"id":"123"},"metadata":"please"}` {
+		t.Error("Unexpected request thread control", lastRequest.URL.Path, string(lastMessage))
+	}
+
+	if err := msng.ReleaseThreadControl(context.Background(), 123, ""); err != nil {
+		t.Fatal(err)
+	}
+	if lastRequest.URL.Path != "/me/release_thread_control" || string(lastMessage) != `{"recipient":{"id":"123"}}` {
+		t.Error("Unexpected release thread control", lastRequest.URL.Path, string(lastMessage))
+	}
+
+	owner, err := msng.GetThreadOwner(context.Background(), 123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner != messenger.PageInboxAppID || lastRequest.URL.Query().Get("recipient") != "123" {
+		t.Error("Expected Page Inbox thread owner, received", owner, "for", lastRequest.URL.RawQuery)
+	}
+
+	receivers, err := msng.GetSecondaryReceivers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receivers) != 1 || receivers[0].ID != 12345678910 {
+		t.Error("Expected one secondary receiver, received", receivers)
+	}
+}
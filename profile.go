@@ -0,0 +1,135 @@
+package messenger
+
+import (
+	"context"
+	"strings"
+)
+
+// ProfileField is name of Messenger Profile property, used for getting and deleting profile properties
+type ProfileField string
+
+const (
+	// ProfileFieldGetStarted for Get Started button
+	ProfileFieldGetStarted = ProfileField("get_started")
+
+	// ProfileFieldGreeting for greeting text shown before conversation starts
+	ProfileFieldGreeting = ProfileField("greeting")
+
+	// ProfileFieldIceBreakers for ice breaker questions shown before conversation starts
+	ProfileFieldIceBreakers = ProfileField("ice_breakers")
+
+	// ProfileFieldWhitelistedDomains for domains allowed in webviews and plugins
+	ProfileFieldWhitelistedDomains = ProfileField("whitelisted_domains")
+
+	// ProfileFieldHomeURL for chat extension home URL
+	ProfileFieldHomeURL = ProfileField("home_url")
+
+	// ProfileFieldPersistentMenu for page-wide persistent menu
+	ProfileFieldPersistentMenu = ProfileField("persistent_menu")
+)
+
+// DefaultLocale is used for greeting text and other localized properties shown if there is no text for user's locale
+const DefaultLocale = "default"
+
+// MessengerProfile holds properties of your page's Messenger Profile
+// Only properties which are set are changed when used with SetMessengerProfile
+type MessengerProfile struct {
+	GetStarted         *GetStarted      `json:"get_started,omitempty"`
+	Greeting           []Greeting       `json:"greeting,omitempty"`
+	IceBreakers        []IceBreaker     `json:"ice_breakers,omitempty"`
+	WhitelistedDomains []string         `json:"whitelisted_domains,omitempty"`
+	HomeURL            *HomeURL         `json:"home_url,omitempty"`
+	PersistentMenu     []PersistentMenu `json:"persistent_menu,omitempty"`
+}
+
+// GetStarted button is shown to new users, when pressed payload is sent back to webhook as postback
+type GetStarted struct {
+	Payload string `json:"payload"`
+}
+
+// Greeting text for locale, use DefaultLocale for text shown to users without localized greeting
+// Text can contain {{user_first_name}}, {{user_last_name}} and {{user_full_name}} placeholders
+type Greeting struct {
+	Locale string `json:"locale"`
+	Text   string `json:"text"`
+}
+
+// IceBreaker question shown to new users, when pressed payload is sent back to webhook as postback
+type IceBreaker struct {
+	Question string `json:"question"`
+	Payload  string `json:"payload"`
+}
+
+// HomeURL of chat extension opened in webview from composer
+type HomeURL struct {
+	URL                string `json:"url"`
+	WebviewHeightRatio string `json:"webview_height_ratio"`
+	WebviewShareButton string `json:"webview_share_button,omitempty"`
+	InTest             bool   `json:"in_test"`
+}
+
+type profileResponse struct {
+	Data []MessengerProfile `json:"data"`
+}
+
+type profileDelete struct {
+	Fields []ProfileField `json:"fields"`
+}
+
+// SetMessengerProfile sets properties of Messenger Profile, properties that are not set in p are not changed
+func (msng *Messenger) SetMessengerProfile(ctx context.Context, p MessengerProfile) error {
+	return msng.callJSON(ctx, "POST", "me/messenger_profile", p, nil)
+}
+
+// GetMessengerProfile returns Messenger Profile with requested fields
+func (msng *Messenger) GetMessengerProfile(ctx context.Context, fields ...ProfileField) (MessengerProfile, error) {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f)
+	}
+
+	var resp profileResponse
+	if err := msng.callJSON(ctx, "GET", "me/messenger_profile?fields="+strings.Join(names, ","), nil, &resp); err != nil {
+		return MessengerProfile{}, err
+	}
+	if len(resp.Data) == 0 {
+		return MessengerProfile{}, nil
+	}
+	return resp.Data[0], nil
+}
+
+// DeleteMessengerProfile removes fields from Messenger Profile
+func (msng *Messenger) DeleteMessengerProfile(ctx context.Context, fields ...ProfileField) error {
+	return msng.callJSON(ctx, "DELETE", "me/messenger_profile", profileDelete{Fields: fields}, nil)
+}
+
+// SetGetStarted sets Get Started button, payload is sent back to webhook as postback when user presses the button
+func (msng *Messenger) SetGetStarted(ctx context.Context, payload string) error {
+	return msng.SetMessengerProfile(ctx, MessengerProfile{GetStarted: &GetStarted{Payload: payload}})
+}
+
+// SetGreeting sets greeting text shown before conversation starts for all locales
+// Use SetLocalizedGreeting if you want to set different greeting for different locales
+func (msng *Messenger) SetGreeting(ctx context.Context, text string) error {
+	return msng.SetLocalizedGreeting(ctx, Greeting{Locale: DefaultLocale, Text: text})
+}
+
+// SetLocalizedGreeting sets greeting texts for locales, one of greetings should have DefaultLocale
+func (msng *Messenger) SetLocalizedGreeting(ctx context.Context, greetings ...Greeting) error {
+	return msng.SetMessengerProfile(ctx, MessengerProfile{Greeting: greetings})
+}
+
+// SetIceBreakers sets up to 4 questions shown to user before conversation starts
+func (msng *Messenger) SetIceBreakers(ctx context.Context, iceBreakers ...IceBreaker) error {
+	return msng.SetMessengerProfile(ctx, MessengerProfile{IceBreakers: iceBreakers})
+}
+
+// SetWhitelistedDomains sets domains allowed in webviews and plugins, up to 50 domains
+func (msng *Messenger) SetWhitelistedDomains(ctx context.Context, domains ...string) error {
+	return msng.SetMessengerProfile(ctx, MessengerProfile{WhitelistedDomains: domains})
+}
+
+// SetHomeURL sets chat extension home URL, URL domain must be whitelisted
+func (msng *Messenger) SetHomeURL(ctx context.Context, h HomeURL) error {
+	return msng.SetMessengerProfile(ctx, MessengerProfile{HomeURL: &h})
+}
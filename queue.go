@@ -0,0 +1,403 @@
+package messenger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultQueueAttempts is number of send attempts for queued message if Queue Retry MaxAttempts is not set
+const defaultQueueAttempts = 10
+
+// QueueItem is message waiting in Queue to be sent
+// Recipient is key of message recipient, items with the same recipient are sent in Seq order
+type QueueItem struct {
+	ID        string          `json:"id"`
+	Seq       uint64          `json:"seq"`
+	Recipient string          `json:"recipient"`
+	Message   json.RawMessage `json:"message"`
+	Attempts  int             `json:"attempts"`
+	Created   time.Time       `json:"created"`
+}
+
+// QueueStore persists queued messages until they are sent, so they survive process restart
+// Implement it if you want to keep queue in your database, or use NewMemoryQueueStore or NewFileQueueStore
+type QueueStore interface {
+	// Save stores new item or replaces existing item with the same ID
+	Save(item QueueItem) error
+	// Delete removes item, deleting item which does not exist is not an error
+	Delete(id string) error
+	// Load returns all stored items
+	Load() ([]QueueItem, error)
+}
+
+// Queue sends messages asynchronously, storing them in QueueStore until they are sent
+// Messages for the same recipient are sent one by one in order they were enqueued and messages
+// failed with retryable or network error are retried with exponential backoff. Queue is safe for concurrent use
+type Queue struct {
+	// Retry sets number of attempts and delay between them, MaxAttempts is 10 if not set
+	// Messenger Retry is not used for queued messages. Unlike it, network errors are retried too
+	// and Facebook Retry-After is always respected
+	Retry RetryPolicy
+
+	// Completed is called once message is sent or failed for the last time, err is nil if message was sent
+	Completed func(item QueueItem, resp FacebookResponse, err error)
+
+	msng  *Messenger
+	store QueueStore
+
+	mu      sync.Mutex
+	seq     uint64
+	loaded  bool
+	pending map[string]*recipientQueue
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+}
+
+// recipientQueue holds pending items of one recipient, it is owned by single worker
+type recipientQueue struct {
+	items []QueueItem
+}
+
+// NewQueue creates new queue which sends messages using msng and keeps them in store until they are sent
+// Call Start to start sending stored and newly enqueued messages
+func NewQueue(msng *Messenger, store QueueStore) *Queue {
+	return &Queue{
+		msng:  msng,
+		store: store,
+	}
+}
+
+// Start loads messages left in store and starts sending them together with newly enqueued messages
+func (q *Queue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx != nil {
+		return errors.New("FB Error: queue already started")
+	}
+
+	items, err := q.load()
+	if err != nil {
+		return err
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.wg = &sync.WaitGroup{}
+	q.pending = make(map[string]*recipientQueue)
+	for _, item := range items {
+		q.schedule(item)
+	}
+	return nil
+}
+
+// load returns stored items in Seq order and makes sure new items are numbered after them, q.mu must be held
+func (q *Queue) load() ([]QueueItem, error) {
+	items, err := q.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Seq < items[j].Seq })
+	if n := len(items); n > 0 && items[n-1].Seq > q.seq {
+		q.seq = items[n-1].Seq
+	}
+	q.loaded = true
+	return items, nil
+}
+
+// Stop stops sending and waits for messages currently being sent, unsent messages stay in store
+func (q *Queue) Stop() {
+	// clear ctx first so concurrent Enqueue doesn't start workers which would outlive Stop
+	q.mu.Lock()
+	cancel, wg := q.cancel, q.wg
+	q.ctx, q.cancel, q.wg = nil, nil, nil
+	q.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	wg.Wait()
+}
+
+// Enqueue stores message and returns its ID, message is sent once queue is started
+func (q *Queue) Enqueue(m Message) (string, error) {
+	if v, ok := m.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return "", err
+		}
+	}
+
+	s, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	id, err := newQueueID()
+	if err != nil {
+		return "", err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loaded {
+		// items left in store must be sent before this one even if queue is not started yet
+		if _, err := q.load(); err != nil {
+			return "", err
+		}
+	}
+	q.seq++
+	item := QueueItem{
+		ID:        id,
+		Seq:       q.seq,
+		Recipient: messageRecipient(s),
+		Message:   s,
+		Created:   time.Now(),
+	}
+	if err := q.store.Save(item); err != nil {
+		return "", err
+	}
+	if q.ctx != nil {
+		q.schedule(item)
+	}
+	return id, nil
+}
+
+// schedule adds item to recipient's pending items and starts worker for recipient if needed, q.mu must be held
+func (q *Queue) schedule(item QueueItem) {
+	rq, running := q.pending[item.Recipient]
+	if !running {
+		rq = &recipientQueue{}
+		q.pending[item.Recipient] = rq
+		q.wg.Add(1)
+		go q.run(q.ctx, q.wg, item.Recipient, rq)
+	}
+	rq.items = append(rq.items, item)
+}
+
+// run sends items of rq one by one until there are none left or ctx is done
+func (q *Queue) run(ctx context.Context, wg *sync.WaitGroup, recipient string, rq *recipientQueue) {
+	defer wg.Done()
+	for {
+		q.mu.Lock()
+		if len(rq.items) == 0 || ctx.Err() != nil {
+			// after restart recipient can already have new worker, remove only our own items
+			if q.pending[recipient] == rq {
+				delete(q.pending, recipient)
+			}
+			q.mu.Unlock()
+			return
+		}
+		item := rq.items[0]
+		q.mu.Unlock()
+
+		q.deliver(ctx, item)
+
+		q.mu.Lock()
+		rq.items = rq.items[1:]
+		q.mu.Unlock()
+	}
+}
+
+// deliver sends item until it succeeds, fails for the last time or ctx is done
+func (q *Queue) deliver(ctx context.Context, item QueueItem) {
+	maxAttempts := q.Retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultQueueAttempts
+	}
+
+	for {
+		resp, err := q.send(ctx, item)
+		if ctx.Err() != nil {
+			return
+		}
+		item.Attempts++
+
+		var fbErr *FacebookError
+		final := err == nil || item.Attempts >= maxAttempts || errors.As(err, &fbErr) && !fbErr.IsRetryable()
+		if final {
+			if err := q.store.Delete(item.ID); err != nil {
+				log.Println("FB Error: deleting queued message", item.ID, "failed, it can be sent again after restart:", err)
+			}
+			if q.Completed != nil {
+				q.Completed(item, resp, err)
+			}
+			return
+		}
+		if err := q.store.Save(item); err != nil {
+			log.Println("FB Error: saving attempts of queued message", item.ID, "failed:", err)
+		}
+
+		d := q.Retry.backoff(item.Attempts)
+		if fbErr != nil && fbErr.RetryAfter > d {
+			d = fbErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d):
+		}
+	}
+}
+
+// send makes single attempt to send item, retries are done by deliver so Messenger Retry is not used
+func (q *Queue) send(ctx context.Context, item QueueItem) (FacebookResponse, error) {
+	log.Println("MESSAGE:", string(item.Message))
+	var resp FacebookResponse
+	if err := q.msng.do(q.msng.recipientContext(ctx, item.Message), "POST", "me/messages", "application/json", item.Message, &resp); err != nil {
+		return FacebookResponse{}, err
+	}
+	return resp, nil
+}
+
+func newQueueID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// MemoryQueueStore is in-memory QueueStore safe for concurrent use, messages are lost on restart
+type MemoryQueueStore struct {
+	mu    sync.Mutex
+	items map[string]QueueItem
+}
+
+// NewMemoryQueueStore creates new empty in-memory queue store
+func NewMemoryQueueStore() *MemoryQueueStore {
+	return &MemoryQueueStore{items: make(map[string]QueueItem)}
+}
+
+// Save stores item
+func (s *MemoryQueueStore) Save(item QueueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[item.ID] = item
+	return nil
+}
+
+// Delete removes item
+func (s *MemoryQueueStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, id)
+	return nil
+}
+
+// Load returns all stored items
+func (s *MemoryQueueStore) Load() ([]QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]QueueItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// FileQueueStore is QueueStore which keeps each item as JSON file in directory
+type FileQueueStore struct {
+	dir string
+}
+
+// NewFileQueueStore creates queue store in dir, directory is created if it doesn't exist
+func NewFileQueueStore(dir string) (*FileQueueStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileQueueStore{dir: dir}, nil
+}
+
+// Save writes item to file, file is synced to disk and replaced atomically so partially written item is never loaded
+func (s *FileQueueStore) Save(item QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, item.ID+".tmp")
+	if err := writeFileSync(tmp, data); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s.path(item.ID)); err != nil {
+		return err
+	}
+	return s.syncDir()
+}
+
+// writeFileSync writes data to file and syncs it to disk before returning
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir syncs directory so renamed file survives crash, directory sync is not supported on all platforms so its error is ignored
+func (s *FileQueueStore) syncDir() error {
+	d, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	d.Sync()
+	return d.Close()
+}
+
+// Delete removes item file
+func (s *FileQueueStore) Delete(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Load reads all items from directory, files which can't be decoded are renamed
+// with ".corrupt" suffix and skipped so they don't block other items
+func (s *FileQueueStore) Load() ([]QueueItem, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []QueueItem
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		name := filepath.Join(s.dir, f.Name())
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var item QueueItem
+		if err := json.Unmarshal(data, &item); err != nil || item.ID == "" {
+			log.Println("FB Error: skipping corrupt queue item", name, err)
+			os.Rename(name, name+".corrupt")
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *FileQueueStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,233 @@
+package messenger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// usageSlowdownPercent is Graph API usage in percent when RateLimiter starts slowing down
+	usageSlowdownPercent = 75
+
+	// minUsageFactor is the lowest fraction of configured rate used when usage is close to 100%
+	minUsageFactor = 0.1
+
+	// throttledPause is time RateLimiter pauses all calls when usage reached 100% and Facebook didn't say how long to wait
+	throttledPause = time.Minute
+
+	// maxIdleRecipients is number of recipient buckets kept before idle ones are removed
+	maxIdleRecipients = 10000
+)
+
+// recipientKey is context key for recipient of sent message, used for per-recipient rate limiting
+type recipientKey struct{}
+
+// RateLimiter is token bucket rate limiter for Graph API calls, set it as Messenger RateLimit
+// Calls are limited per page and messages are additionally limited per recipient. Limiter reads
+// X-App-Usage, X-Page-Usage and X-Business-Use-Case-Usage headers from Facebook responses and slows
+// down when usage is over 75%, or pauses all calls when usage reaches 100%. It is safe for concurrent use
+type RateLimiter struct {
+	pageRate      float64
+	recipientRate float64
+
+	mu          sync.Mutex
+	page        bucket
+	recipients  map[string]*bucket
+	factor      float64
+	pausedUntil time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates new rate limiter which allows pageRate calls per second for the page
+// and recipientRate messages per second for each recipient, use 0 for recipientRate to limit only per page
+func NewRateLimiter(pageRate, recipientRate float64) *RateLimiter {
+	return &RateLimiter{
+		pageRate:      pageRate,
+		recipientRate: recipientRate,
+		recipients:    make(map[string]*bucket),
+		factor:        1,
+	}
+}
+
+// Wait blocks until call to recipient is allowed or ctx is done, recipient can be "" for calls not sent to user
+func (l *RateLimiter) Wait(ctx context.Context, recipient string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	var reserved []*bucket
+	wait := l.page.reserve(now, l.pageRate*l.factor)
+	if l.pageRate > 0 {
+		reserved = append(reserved, &l.page)
+	}
+	if recipient != "" && l.recipientRate > 0 {
+		b, ok := l.recipients[recipient]
+		if !ok {
+			l.removeIdle(now)
+			b = &bucket{}
+			l.recipients[recipient] = b
+		}
+		if d := b.reserve(now, l.recipientRate*l.factor); d > wait {
+			wait = d
+		}
+		reserved = append(reserved, b)
+	}
+	if d := l.pausedUntil.Sub(now); d > wait {
+		wait = d
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		// call was not made, give tokens back so later calls don't wait for it
+		l.mu.Lock()
+		for _, b := range reserved {
+			b.tokens++
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// update adjusts rate according to Graph API usage headers in Facebook response
+func (l *RateLimiter) update(h http.Header) {
+	if l == nil {
+		return
+	}
+
+	percent, regain, ok := usage(h)
+	if !ok {
+		// response without usage headers, like from proxy, says nothing about current usage
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case percent >= 100:
+		if regain <= 0 {
+			regain = throttledPause
+		}
+		l.pausedUntil = time.Now().Add(regain)
+		l.factor = minUsageFactor
+	case percent > usageSlowdownPercent:
+		l.factor = 1 - float64(percent-usageSlowdownPercent)/float64(100-usageSlowdownPercent)*(1-minUsageFactor)
+	default:
+		l.factor = 1
+	}
+}
+
+// removeIdle removes buckets of recipients which didn't receive messages long enough to have full bucket again
+func (l *RateLimiter) removeIdle(now time.Time) {
+	if len(l.recipients) < maxIdleRecipients {
+		return
+	}
+	for r, b := range l.recipients {
+		if now.Sub(b.last).Seconds()*l.recipientRate*l.factor >= burst(l.recipientRate) {
+			delete(l.recipients, r)
+		}
+	}
+}
+
+// reserve takes one token from bucket refilled with rate tokens per second and returns time to wait for it
+func (b *bucket) reserve(now time.Time, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	max := burst(rate)
+	if b.last.IsZero() {
+		b.tokens = max
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > max {
+			b.tokens = max
+		}
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// burst is bucket size for rate, one second worth of calls but at least one call
+func burst(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+// graphUsage is Graph API usage in percent received in X-App-Usage and X-Page-Usage headers,
+// and for each use case in X-Business-Use-Case-Usage header
+type graphUsage struct {
+	Type                        string `json:"type,omitempty"`
+	CallCount                   int    `json:"call_count"`
+	TotalCPUTime                int    `json:"total_cputime"`
+	TotalTime                   int    `json:"total_time"`
+	EstimatedTimeToRegainAccess int    `json:"estimated_time_to_regain_access,omitempty"`
+}
+
+// businessUseCaseUsage is value of X-Business-Use-Case-Usage header, it maps business ID to usage of each use case
+type businessUseCaseUsage map[string][]graphUsage
+
+// max returns the highest of usage percentages
+func (u graphUsage) max() int {
+	m := u.CallCount
+	if u.TotalCPUTime > m {
+		m = u.TotalCPUTime
+	}
+	if u.TotalTime > m {
+		m = u.TotalTime
+	}
+	return m
+}
+
+// usage returns the highest Graph API usage percent from response headers and estimated time to regain access
+// ok is false if response has no usage headers
+func usage(h http.Header) (percent int, regain time.Duration, ok bool) {
+	for _, name := range []string{"X-App-Usage", "X-Page-Usage"} {
+		var u graphUsage
+		if json.Unmarshal([]byte(h.Get(name)), &u) != nil {
+			continue
+		}
+		ok = true
+		if u.max() > percent {
+			percent = u.max()
+		}
+	}
+
+	var buc businessUseCaseUsage
+	if json.Unmarshal([]byte(h.Get("X-Business-Use-Case-Usage")), &buc) == nil {
+		ok = true
+		for _, cases := range buc {
+			for _, u := range cases {
+				if u.max() > percent {
+					percent = u.max()
+				}
+				if d := time.Duration(u.EstimatedTimeToRegainAccess) * time.Minute; d > regain {
+					regain = d
+				}
+			}
+		}
+	}
+	return percent, regain, ok
+}
@@ -0,0 +1,141 @@
+package messenger
+
+import "time"
+
+// ReceiptMessage struct used for sending order confirmations to messenger
+type ReceiptMessage struct {
+	Message          receiptMessageContent `json:"message"`
+	Recipient        Recipient             `json:"recipient"`
+	NotificationType NotificationType      `json:"notification_type,omitempty"`
+	MessagingType    MessagingType         `json:"messaging_type,omitempty"`
+	Tag              MessageTag            `json:"tag,omitempty"`
+}
+
+type receiptMessageContent struct {
+	Attachment receiptAttachment `json:"attachment"`
+}
+
+type receiptAttachment struct {
+	Type    string         `json:"type"`
+	Payload receiptPayload `json:"payload"`
+}
+
+type receiptPayload struct {
+	TemplateType  string              `json:"template_type"`
+	RecipientName string              `json:"recipient_name"`
+	OrderNumber   string              `json:"order_number"`
+	Currency      string              `json:"currency"`
+	PaymentMethod string              `json:"payment_method"`
+	OrderURL      string              `json:"order_url,omitempty"`
+	Timestamp     int64               `json:"timestamp,string,omitempty"`
+	Elements      []ReceiptElement    `json:"elements,omitempty"`
+	Address       *ReceiptAddress     `json:"address,omitempty"`
+	Summary       ReceiptSummary      `json:"summary"`
+	Adjustments   []ReceiptAdjustment `json:"adjustments,omitempty"`
+}
+
+// ReceiptElement is ordered item in Receipt message
+type ReceiptElement struct {
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle,omitempty"`
+	Quantity int     `json:"quantity,omitempty"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency,omitempty"`
+	ImageURL string  `json:"image_url,omitempty"`
+}
+
+// ReceiptAddress is shipping address in Receipt message
+type ReceiptAddress struct {
+	Street1    string `json:"street_1"`
+	Street2    string `json:"street_2,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	State      string `json:"state"`
+	Country    string `json:"country"`
+}
+
+// ReceiptSummary with order totals in Receipt message, TotalCost is mandatory
+type ReceiptSummary struct {
+	Subtotal     float64 `json:"subtotal,omitempty"`
+	ShippingCost float64 `json:"shipping_cost,omitempty"`
+	TotalTax     float64 `json:"total_tax,omitempty"`
+	TotalCost    float64 `json:"total_cost"`
+}
+
+// ReceiptAdjustment is discount or other adjustment of the order in Receipt message
+type ReceiptAdjustment struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+// NewReceiptMessage creates new Receipt Template message for userID
+// currency is ISO 4217 code like USD or EUR, paymentMethod is free text like "Visa 2345"
+// Add items with AddNewElement and set totals with SetSummary before sending
+func (msng Messenger) NewReceiptMessage(userID int64, recipientName, orderNumber, currency, paymentMethod string) ReceiptMessage {
+	return msng.NewReceiptMessageTo(Recipient{ID: userID}, recipientName, orderNumber, currency, paymentMethod)
+}
+
+// NewReceiptMessageTo creates new Receipt Template message for recipient r
+func (msng Messenger) NewReceiptMessageTo(r Recipient, recipientName, orderNumber, currency, paymentMethod string) ReceiptMessage {
+	return ReceiptMessage{
+		Recipient:     r,
+		MessagingType: MessagingTypeResponse,
+		Message: receiptMessageContent{
+			Attachment: receiptAttachment{
+				Type: string(AttachmentTypeTemplate),
+				Payload: receiptPayload{
+					TemplateType:  string(TemplateTypeReceipt),
+					RecipientName: recipientName,
+					OrderNumber:   orderNumber,
+					Currency:      currency,
+					PaymentMethod: paymentMethod,
+				},
+			},
+		},
+	}
+}
+
+// AddNewElement adds ordered item to Receipt message with defined title, subtitle, quantity, price and image url
+// Title and price are mandatory. If not used set "" for other string params and 0 for quantity
+func (m *ReceiptMessage) AddNewElement(title, subtitle string, quantity int, price float64, imageURL string) {
+	m.AddElement(ReceiptElement{
+		Title:    title,
+		Subtitle: subtitle,
+		Quantity: quantity,
+		Price:    price,
+		ImageURL: imageURL,
+	})
+}
+
+// AddElement adds ordered item e to Receipt message, Receipt messages can have up to 100 elements
+func (m *ReceiptMessage) AddElement(e ReceiptElement) {
+	m.Message.Attachment.Payload.Elements = append(m.Message.Attachment.Payload.Elements, e)
+}
+
+// AddAdjustment adds discount or other adjustment of the order to Receipt message
+func (m *ReceiptMessage) AddAdjustment(name string, amount float64) {
+	m.Message.Attachment.Payload.Adjustments = append(m.Message.Attachment.Payload.Adjustments, ReceiptAdjustment{
+		Name:   name,
+		Amount: amount,
+	})
+}
+
+// SetSummary sets order totals of Receipt message
+func (m *ReceiptMessage) SetSummary(s ReceiptSummary) {
+	m.Message.Attachment.Payload.Summary = s
+}
+
+// SetAddress sets shipping address of Receipt message
+func (m *ReceiptMessage) SetAddress(a ReceiptAddress) {
+	m.Message.Attachment.Payload.Address = &a
+}
+
+// SetTimestamp sets time when the order was placed
+func (m *ReceiptMessage) SetTimestamp(t time.Time) {
+	m.Message.Attachment.Payload.Timestamp = t.Unix()
+}
+
+// SetOrderURL sets URL of the order on your site
+func (m *ReceiptMessage) SetOrderURL(URL string) {
+	m.Message.Attachment.Payload.OrderURL = URL
+}
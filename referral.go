@@ -0,0 +1,35 @@
+package messenger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+)
+
+// MeLink returns m.me link to conversation with page (page username or ID) with ref parameter
+// ref is received in FacebookReferral when user opens the link, use EncodeRef if you want to send structured data
+func MeLink(page, ref string) string {
+	link := "https://m.me/" + url.PathEscape(page)
+	if ref != "" {
+		link += "?ref=" + url.QueryEscape(ref)
+	}
+	return link
+}
+
+// EncodeRef encodes v as JSON and then as URL safe base64 string that can be used as ref parameter of m.me link
+func EncodeRef(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeRef decodes ref created with EncodeRef into v
+func DecodeRef(ref string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(ref)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
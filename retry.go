@@ -0,0 +1,77 @@
+package messenger
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryPolicy for Graph API calls failed with retryable error, see FacebookError IsRetryable
+// Delay between attempts grows exponentially from BaseDelay up to MaxDelay with random jitter.
+// If Facebook asks to wait longer than MaxDelay (Retry-After or X-Business-Use-Case-Usage headers)
+// call is not retried and error is returned
+type RetryPolicy struct {
+	MaxAttempts int           // total number of attempts including the first one
+	BaseDelay   time.Duration // delay after first attempt, 500ms if not set
+	MaxDelay    time.Duration // maximum delay between attempts, 30s if not set
+}
+
+// delay returns time to wait before next attempt and false if call should not be retried
+func (p *RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	var fbErr *FacebookError
+	if !errors.As(err, &fbErr) || !fbErr.IsRetryable() {
+		return 0, false
+	}
+
+	if fbErr.RetryAfter > p.maxDelay() {
+		return 0, false
+	}
+
+	d := p.backoff(attempt)
+	if fbErr.RetryAfter > d {
+		d = fbErr.RetryAfter
+	}
+	return d, true
+}
+
+// backoff returns exponential delay with jitter after attempt, not counting time Facebook asked to wait
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, max := p.BaseDelay, p.maxDelay()
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+
+	d := base << uint(attempt-1)
+	if d > max || d <= 0 {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (p *RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return defaultRetryMaxDelay
+	}
+	return p.MaxDelay
+}
+
+// retryAfter returns time Facebook asked to wait before next call, from Retry-After header
+// or estimated time to regain access (in minutes) from X-Business-Use-Case-Usage header
+func retryAfter(h http.Header) time.Duration {
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	_, regain, _ := usage(h)
+	return regain
+}
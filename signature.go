@@ -0,0 +1,63 @@
+package messenger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrMissingSignature is returned by VerifySignature if request has no X-Hub-Signature-256 or X-Hub-Signature header
+	ErrMissingSignature = errors.New("FB Error: missing request signature")
+
+	// ErrInvalidSignature is returned by VerifySignature if request signature doesn't match the body
+	ErrInvalidSignature = errors.New("FB Error: invalid request signature")
+)
+
+// VerifySignature checks X-Hub-Signature-256 header (or legacy X-Hub-Signature SHA1 header if the first one is not present)
+// sent by Facebook against the raw request body signed with your app secret.
+// It returns the raw body so you don't have to read it again. Request Body is replaced
+// with a new reader over the same data, so DecodeRequest can still be used afterwards.
+func VerifySignature(r *http.Request, secret string) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var (
+		sig    string
+		prefix string
+		hf     func() hash.Hash
+	)
+	if sig = r.Header.Get("X-Hub-Signature-256"); sig != "" {
+		prefix, hf = "sha256=", sha256.New
+	} else if sig = r.Header.Get("X-Hub-Signature"); sig != "" {
+		prefix, hf = "sha1=", sha1.New
+	} else {
+		return body, ErrMissingSignature
+	}
+
+	if !strings.HasPrefix(sig, prefix) {
+		return body, ErrInvalidSignature
+	}
+	expected, err := hex.DecodeString(sig[len(prefix):])
+	if err != nil {
+		return body, ErrInvalidSignature
+	}
+
+	mac := hmac.New(hf, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return body, ErrInvalidSignature
+	}
+	return body, nil
+}
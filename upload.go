@@ -0,0 +1,187 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"sync"
+)
+
+// AttachmentCache stores attachment IDs returned by Facebook, keyed by attachment URL
+// or by "sha256:" followed by hex encoded hash of uploaded content
+// Implement it if you want to keep IDs in your database, or use NewMemoryAttachmentCache
+type AttachmentCache interface {
+	Get(key string) (attachmentID string, ok bool)
+	Set(key, attachmentID string)
+}
+
+// MemoryAttachmentCache is in-memory AttachmentCache safe for concurrent use
+type MemoryAttachmentCache struct {
+	mu  sync.RWMutex
+	ids map[string]string
+}
+
+// NewMemoryAttachmentCache creates new empty in-memory attachment cache
+func NewMemoryAttachmentCache() *MemoryAttachmentCache {
+	return &MemoryAttachmentCache{ids: make(map[string]string)}
+}
+
+// Get returns cached attachment ID for key
+func (c *MemoryAttachmentCache) Get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.ids[key]
+	return id, ok
+}
+
+// Set stores attachment ID for key
+func (c *MemoryAttachmentCache) Set(key, attachmentID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[key] = attachmentID
+}
+
+// uploadMessage is message part of Attachment Upload API request
+type uploadMessage struct {
+	Message genericMessageContent `json:"message"`
+}
+
+// UploadAttachment uploads content of r to Facebook using Attachment Upload API and returns attachment ID
+// which can be used with NewAttachmentMessage. filename is used for detecting content type.
+// If AttachmentCache is set, the same content is uploaded only once
+func (msng *Messenger) UploadAttachment(ctx context.Context, attType AttachmentType, r io.Reader, filename string) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	key := "sha256:" + hex.EncodeToString(sum[:])
+	if msng.AttachmentCache != nil {
+		if id, ok := msng.AttachmentCache.Get(key); ok {
+			return id, nil
+		}
+	}
+
+	msg, _ := json.Marshal(newUploadMessage(attType, ""))
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("message", string(msg))
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+		"name":     "filedata",
+		"filename": filepath.Base(filename),
+	}))
+	h.Set("Content-Type", contentType)
+	fw, err := mw.CreatePart(h)
+	if err != nil {
+		return "", err
+	}
+	fw.Write(data)
+	mw.Close()
+
+	return msng.upload(ctx, key, mw.FormDataContentType(), body.Bytes())
+}
+
+// UploadAttachmentURL uploads attachment from URL to Facebook using Attachment Upload API and returns attachment ID
+// which can be used with NewAttachmentMessage. If AttachmentCache is set, the same URL is uploaded only once
+func (msng *Messenger) UploadAttachmentURL(ctx context.Context, attType AttachmentType, URL string) (string, error) {
+	if msng.AttachmentCache != nil {
+		if id, ok := msng.AttachmentCache.Get(URL); ok {
+			return id, nil
+		}
+	}
+
+	s, _ := json.Marshal(newUploadMessage(attType, URL))
+	return msng.upload(ctx, URL, "application/json", s)
+}
+
+func newUploadMessage(attType AttachmentType, URL string) uploadMessage {
+	return uploadMessage{
+		Message: genericMessageContent{
+			Attachment: &attachment{
+				Type:    string(attType),
+				Payload: payload{URL: URL, IsReusable: true},
+			},
+		},
+	}
+}
+
+// upload posts upload request and stores returned attachment ID in cache under key
+func (msng *Messenger) upload(ctx context.Context, key, contentType string, body []byte) (string, error) {
+	resp, err := msng.post(ctx, "me/message_attachments", contentType, body)
+	if err != nil {
+		return "", err
+	}
+	if resp.AttachmentID == "" {
+		return "", errors.New("FB Error: no attachment ID in upload response")
+	}
+
+	if msng.AttachmentCache != nil {
+		msng.AttachmentCache.Set(key, resp.AttachmentID)
+	}
+	return resp.AttachmentID, nil
+}
+
+// cacheAttachment stores attachment ID returned after sending reusable attachment message by URL
+func (msng *Messenger) cacheAttachment(m Message, resp FacebookResponse) {
+	if msng.AttachmentCache == nil || resp.AttachmentID == "" {
+		return
+	}
+
+	var am AttachmentMessage
+	switch v := m.(type) {
+	case AttachmentMessage:
+		am = v
+	case *AttachmentMessage:
+		am = *v
+	default:
+		return
+	}
+
+	if am.Message.Attachment != nil && am.Message.Attachment.Payload.URL != "" {
+		msng.AttachmentCache.Set(am.Message.Attachment.Payload.URL, resp.AttachmentID)
+	}
+}
+
+// DownloadAttachment downloads content of attachment received from user and writes it to w
+func (msng *Messenger) DownloadAttachment(ctx context.Context, a FacebookAttachment, w io.Writer) error {
+	if a.Payload.URL == "" {
+		return fmt.Errorf("FB Error: %s attachment has no content to download", a.Type)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", a.Payload.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := msng.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("FB Error: downloading attachment failed with status %s", resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
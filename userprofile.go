@@ -0,0 +1,159 @@
+package messenger
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserProfileField is name of user profile field that can be requested with GetUserProfile
+type UserProfileField string
+
+const (
+	// UserProfileFieldFirstName for user's first name
+	UserProfileFieldFirstName = UserProfileField("first_name")
+
+	// UserProfileFieldLastName for user's last name
+	UserProfileFieldLastName = UserProfileField("last_name")
+
+	// UserProfileFieldProfilePic for URL of user's profile picture, URL expires after some time
+	UserProfileFieldProfilePic = UserProfileField("profile_pic")
+
+	// UserProfileFieldLocale for user's locale, like en_US, requires additional permission
+	UserProfileFieldLocale = UserProfileField("locale")
+
+	// UserProfileFieldTimezone for user's timezone as offset from UTC, requires additional permission
+	UserProfileFieldTimezone = UserProfileField("timezone")
+
+	// UserProfileFieldGender for user's gender, requires additional permission
+	UserProfileFieldGender = UserProfileField("gender")
+)
+
+const (
+	// DefaultUserProfileCacheSize is number of profiles kept in cache created by New
+	DefaultUserProfileCacheSize = 1000
+
+	// DefaultUserProfileCacheTTL is time profiles are kept in cache created by New
+	DefaultUserProfileCacheTTL = time.Hour
+)
+
+// defaultUserProfileFields are requested if GetUserProfile is called without fields
+var defaultUserProfileFields = []UserProfileField{UserProfileFieldFirstName, UserProfileFieldLastName, UserProfileFieldProfilePic}
+
+// UserProfile of user who sent message to your page, only requested fields are set
+type UserProfile struct {
+	ID         int64   `json:"id,string"`
+	FirstName  string  `json:"first_name,omitempty"`
+	LastName   string  `json:"last_name,omitempty"`
+	ProfilePic string  `json:"profile_pic,omitempty"`
+	Locale     string  `json:"locale,omitempty"`
+	Timezone   float64 `json:"timezone,omitempty"`
+	Gender     string  `json:"gender,omitempty"`
+}
+
+// UserProfileCache stores user profiles so GetUserProfile doesn't have to call Graph API on every message
+// Key is made of user ID and requested fields. Implement it if you want to keep profiles in your storage
+type UserProfileCache interface {
+	Get(key string) (UserProfile, bool)
+	Set(key string, p UserProfile)
+}
+
+// GetUserProfile returns profile of user psid with requested fields, or first name, last name and profile picture
+// if no fields are requested. If UserProfileCache is set, profile is returned from cache if possible
+func (msng *Messenger) GetUserProfile(ctx context.Context, psid int64, fields ...UserProfileField) (UserProfile, error) {
+	if len(fields) == 0 {
+		fields = defaultUserProfileFields
+	}
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f)
+	}
+	key := strconv.FormatInt(psid, 10) + "?fields=" + strings.Join(names, ",")
+
+	if msng.UserProfileCache != nil {
+		if p, ok := msng.UserProfileCache.Get(key); ok {
+			return p, nil
+		}
+	}
+
+	var p UserProfile
+	if err := msng.callJSON(ctx, "GET", key, nil, &p); err != nil {
+		return UserProfile{}, err
+	}
+
+	if msng.UserProfileCache != nil {
+		msng.UserProfileCache.Set(key, p)
+	}
+	return p, nil
+}
+
+// LRUUserProfileCache is in-memory UserProfileCache which keeps up to size most recently used profiles
+// for ttl duration. It is safe for concurrent use
+type LRUUserProfileCache struct {
+	size int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	profile UserProfile
+	expires time.Time
+}
+
+// NewLRUUserProfileCache creates new in-memory LRU cache for up to size profiles kept for ttl duration
+func NewLRUUserProfileCache(size int, ttl time.Duration) *LRUUserProfileCache {
+	return &LRUUserProfileCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns cached profile for key if it is not expired
+func (c *LRUUserProfileCache) Get(key string) (UserProfile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return UserProfile{}, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return UserProfile{}, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.profile, true
+}
+
+// Set stores profile for key, removing least recently used profile if cache is full
+func (c *LRUUserProfileCache) Set(key string, p UserProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.profile, e.expires = p, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, profile: p, expires: expires})
+	if c.order.Len() > c.size {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.items, el.Value.(*lruEntry).key)
+	}
+}
@@ -1,83 +1,32 @@
 package messenger
 
 import (
-	"bytes"
-	"encoding/json"
-	"log"
-	"net/http"
+	"context"
+	"errors"
 )
 
-// Welcome struct used for setting messenger welcome message
-type welcome struct {
-	SettingType   string         `json:"setting_type"`
-	ThreadState   string         `json:"thread_state"`
-	CallToActions []callToAction `json:"call_to_actions"`
-}
-
-type welcomeResponse struct {
-	Result string         `json:"result"`
-	Error  *FacebookError `json:"error"`
-}
-
-type callToAction struct {
-	Message interface{} `json:"message,omitempty"`
-}
+// ErrWelcomeMessageUnsupported is returned by SetWelcomeGeneric, Facebook no longer shows structured welcome messages
+var ErrWelcomeMessageUnsupported = errors.New("FB Error: welcome message is no longer supported, use SetGetStarted and send the message on Get Started postback")
 
 // SetWelcomeText sets plain text welcome message
+//
+// Deprecated: thread settings welcome message is no longer supported by Facebook,
+// SetWelcomeText now sets Messenger Profile greeting, use SetGreeting instead
 func (msng *Messenger) SetWelcomeText(text string) error {
-	m := textMessageContent{Text: text}
-	return msng.setWelcome(&m)
+	return msng.SetGreeting(context.Background(), text)
 }
 
-//SetWelcomeGeneric sets generic template welcome message
+// SetWelcomeGeneric used to set generic template welcome message, it now always returns ErrWelcomeMessageUnsupported
+//
+// Deprecated: thread settings welcome message is no longer supported by Facebook,
+// use SetGetStarted and send the message when Get Started postback is received
 func (msng *Messenger) SetWelcomeGeneric(m GenericMessage) error {
-	return msng.setWelcome(&m.Message)
+	return ErrWelcomeMessageUnsupported
 }
 
 // DeleteWelcome removes welcome message
+//
+// Deprecated: DeleteWelcome now removes Messenger Profile greeting, use DeleteMessengerProfile instead
 func (msng *Messenger) DeleteWelcome() error {
-	return msng.setWelcome(nil)
-}
-
-func (msng *Messenger) setWelcome(m interface{}) error {
-
-	if msng.pageURL == "" {
-		msng.pageURL = apiURL + msng.PageID + "/thread_settings?access_token=" + msng.AccessToken
-		log.Println(msng.pageURL)
-	}
-
-	w := welcome{
-		SettingType:   "call_to_actions",
-		ThreadState:   "new_thread",
-		CallToActions: []callToAction{},
-	}
-
-	// if m exists (no delete welcome) add it to callToAction
-	if m != nil {
-		w.CallToActions = append(w.CallToActions, callToAction{Message: m})
-	}
-
-	s, _ := json.Marshal(w)
-	log.Println("MESSAGE:", string(s))
-	req, err := http.NewRequest("POST", msng.pageURL, bytes.NewBuffer(s))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
-	reply := welcomeResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&reply)
-	if err != nil {
-		return err
-	}
-
-	if reply.Error != nil {
-		return reply.Error.Error()
-	}
-
-	return nil
+	return msng.DeleteMessengerProfile(context.Background(), ProfileFieldGreeting)
 }